@@ -0,0 +1,105 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/grafana/snowflake-prometheus-exporter/collector"
+)
+
+// runningCollector is a collector refreshing its metrics in the background until cancelled.
+type runningCollector struct {
+	col    *collector.Collector
+	cancel context.CancelFunc
+}
+
+// accountCollectors holds the collectors serving /metrics, keyed by account name.
+// In single-account mode, the only collector is keyed by the empty string and its metrics
+// are not labeled with the account.
+type accountCollectors struct {
+	mtx    sync.RWMutex
+	logger *slog.Logger
+	cols   map[string]*runningCollector
+}
+
+// newAccountCollectors creates the collectors of the accounts in the config.
+func newAccountCollectors(logger *slog.Logger, c *collector.Config) *accountCollectors {
+	a := &accountCollectors{
+		logger: logger,
+		cols:   map[string]*runningCollector{},
+	}
+	a.update(c)
+	return a
+}
+
+// update reconciles the collectors with the accounts in the config. Collectors of accounts which
+// remain configured keep their cached metrics, collectors of new accounts are started, and
+// collectors of removed accounts are stopped. The config is assumed to be valid.
+func (a *accountCollectors) update(c *collector.Config) {
+	configs := map[string]*collector.Config{}
+	if c.AccountName != "" {
+		configs[""] = c
+	}
+	for _, account := range c.AccountConfigs() {
+		configs[account.AccountName] = account
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for name, rc := range a.cols {
+		if _, ok := configs[name]; !ok {
+			a.logger.Info("Stopping collector of removed account.", "account", name)
+			rc.cancel()
+			delete(a.cols, name)
+		}
+	}
+
+	for name, config := range configs {
+		if rc, ok := a.cols[name]; ok {
+			rc.col.SetConfig(config)
+			continue
+		}
+
+		var col *collector.Collector
+		if name == "" {
+			col = collector.NewCollector(a.logger, config)
+		} else {
+			col = collector.NewAccountCollector(a.logger.With("account", name), config)
+		}
+
+		// Refresh metrics in the background if a refresh interval is configured
+		ctx, cancel := context.WithCancel(context.Background())
+		go col.Run(ctx)
+		a.cols[name] = &runningCollector{col: col, cancel: cancel}
+	}
+}
+
+// collectors returns the current collectors, sorted by account name.
+func (a *accountCollectors) collectors() []*collector.Collector {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	cols := make([]*collector.Collector, 0, len(a.cols))
+	for _, name := range slices.Sorted(maps.Keys(a.cols)) {
+		cols = append(cols, a.cols[name].col)
+	}
+	return cols
+}
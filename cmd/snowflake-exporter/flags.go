@@ -0,0 +1,254 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/grafana/snowflake-prometheus-exporter/collector"
+	"go.yaml.in/yaml/v2"
+)
+
+// flagValue is a flag which overrides the config file when given on the command line
+// or through its environment variable.
+type flagValue[T comparable] struct {
+	value     *T
+	envar     string
+	setByUser bool
+}
+
+// newFlag adds a flag to the application. typed is the kingpin method which parses the flag's value,
+// for example (*kingpin.FlagClause).String.
+func newFlag[T comparable](app *kingpin.Application, name, help, envar, defaultValue string, typed func(*kingpin.FlagClause) *T) *flagValue[T] {
+	f := &flagValue[T]{envar: envar}
+	clause := app.Flag(name, help).IsSetByUser(&f.setByUser)
+	if envar != "" {
+		clause = clause.Envar(envar)
+	}
+	if defaultValue != "" {
+		clause = clause.Default(defaultValue)
+	}
+	f.value = typed(clause)
+	return f
+}
+
+// given returns whether the flag was given on the command line or through its environment variable.
+func (f *flagValue[T]) given() bool {
+	return f.setByUser || (f.envar != "" && os.Getenv(f.envar) != "")
+}
+
+// override sets dst to the flag's value if the flag was given, or to the flag's default if inFile
+// is false because the config file did not set dst.
+func (f *flagValue[T]) override(dst *T, inFile bool) {
+	if f.given() || !inFile {
+		*dst = *f.value
+	}
+}
+
+// configFlags holds the flags which populate collector.Config.
+type configFlags struct {
+	account             *flagValue[string]
+	username            *flagValue[string]
+	password            *flagValue[string]
+	privateKeyPath      *flagValue[string]
+	privateKeyPassword  *flagValue[string]
+	role                *flagValue[string]
+	warehouse           *flagValue[string]
+	excludeDeleted      *flagValue[bool]
+	enableTracing       *flagValue[bool]
+	refreshInterval     *flagValue[time.Duration]
+	queryTimeout        *flagValue[time.Duration]
+	customQueriesFile   *flagValue[string]
+	maxOpenConns        *flagValue[int]
+	maxIdleConns        *flagValue[int]
+	connMaxLifetime     *flagValue[time.Duration]
+	sessionKeepAlive    *flagValue[bool]
+	queryHistoryLabels  *flagValue[string]
+	queryLatencyBuckets *flagValue[string]
+
+	collectorsEnabled   map[string]*flagValue[bool]
+	collectorsIntervals map[string]*flagValue[time.Duration]
+	collectorsTimeouts  map[string]*flagValue[time.Duration]
+}
+
+// addConfigFlags adds the flags which populate collector.Config to the application.
+func addConfigFlags(app *kingpin.Application) *configFlags {
+	f := &configFlags{
+		account:             newFlag(app, "account", "The account to collect metrics for.", "SNOWFLAKE_EXPORTER_ACCOUNT", "", (*kingpin.FlagClause).String),
+		username:            newFlag(app, "username", "The username for the user used when querying metrics.", "SNOWFLAKE_EXPORTER_USERNAME", "", (*kingpin.FlagClause).String),
+		password:            newFlag(app, "password", "The password for the user used when querying metrics.", "SNOWFLAKE_EXPORTER_PASSWORD", "", (*kingpin.FlagClause).String),
+		privateKeyPath:      newFlag(app, "private-key-path", "The path to the user's RSA private key", "SNOWFLAKE_EXPORTER_PRIVATE_KEY_PATH", "", (*kingpin.FlagClause).String),
+		privateKeyPassword:  newFlag(app, "private-key-password", "The password for the user's RSA private key.", "SNOWFLAKE_EXPORTER_PRIVATE_KEY_PASSWORD", "", (*kingpin.FlagClause).String),
+		role:                newFlag(app, "role", "The role to use when querying metrics.", "SNOWFLAKE_EXPORTER_ROLE", "ACCOUNTADMIN", (*kingpin.FlagClause).String),
+		warehouse:           newFlag(app, "warehouse", "The warehouse to use when querying metrics.", "SNOWFLAKE_EXPORTER_WAREHOUSE", "", (*kingpin.FlagClause).String),
+		excludeDeleted:      newFlag(app, "exclude-deleted-tables", "Exclude deleted tables when collecting table storage metrics.", "", "false", (*kingpin.FlagClause).Bool),
+		enableTracing:       newFlag(app, "enable-tracing", "Enable trace logging for Snowflake connections.", "SNOWFLAKE_EXPORTER_ENABLE_TRACING", "false", (*kingpin.FlagClause).Bool),
+		refreshInterval:     newFlag(app, "refresh-interval", "How often to refresh metrics from Snowflake in the background. If 0, Snowflake is queried on every scrape.", "SNOWFLAKE_EXPORTER_REFRESH_INTERVAL", "0s", (*kingpin.FlagClause).Duration),
+		queryTimeout:        newFlag(app, "query-timeout", "How long each collector's queries may run before being cancelled. If 0, queries are only bounded by the scrape timeout.", "SNOWFLAKE_EXPORTER_QUERY_TIMEOUT", "0s", (*kingpin.FlagClause).Duration),
+		customQueriesFile:   newFlag(app, "custom-queries-file", "Path to a YAML file defining custom queries to export as metrics.", "SNOWFLAKE_EXPORTER_CUSTOM_QUERIES_FILE", "", (*kingpin.FlagClause).String),
+		maxOpenConns:        newFlag(app, "max-open-connections", "Maximum number of open connections to Snowflake. If 0, the number of connections is not limited.", "SNOWFLAKE_EXPORTER_MAX_OPEN_CONNECTIONS", "0", (*kingpin.FlagClause).Int),
+		maxIdleConns:        newFlag(app, "max-idle-connections", "Maximum number of idle connections to Snowflake kept open between refreshes. If 0, defaults to --max-open-connections, or to the number of enabled collectors.", "SNOWFLAKE_EXPORTER_MAX_IDLE_CONNECTIONS", "0", (*kingpin.FlagClause).Int),
+		connMaxLifetime:     newFlag(app, "connection-max-lifetime", "How long a connection to Snowflake may be reused. If 0, connections are reused until they fail.", "SNOWFLAKE_EXPORTER_CONNECTION_MAX_LIFETIME", "0s", (*kingpin.FlagClause).Duration),
+		sessionKeepAlive:    newFlag(app, "client-session-keep-alive", "Keep the Snowflake sessions of idle connections alive between refreshes.", "SNOWFLAKE_EXPORTER_CLIENT_SESSION_KEEP_ALIVE", "false", (*kingpin.FlagClause).Bool),
+		queryLatencyBuckets: newFlag(app, "collector.query_latency.buckets", "Comma-separated upper bounds in seconds of the query_latency collector's histogram buckets. If empty, buckets ranging from 0.1s to 1h are used.", "SNOWFLAKE_EXPORTER_QUERY_LATENCY_BUCKETS", "", (*kingpin.FlagClause).String),
+		queryHistoryLabels:  newFlag(app, "collector.query_history.labels", "Comma-separated labels by which the query_history collector splits its metrics. If empty, all of warehouse_name, user_name, role_name, query_type, and execution_status are used.", "SNOWFLAKE_EXPORTER_QUERY_HISTORY_LABELS", "", (*kingpin.FlagClause).String),
+
+		collectorsEnabled:   map[string]*flagValue[bool]{},
+		collectorsIntervals: map[string]*flagValue[time.Duration]{},
+		collectorsTimeouts:  map[string]*flagValue[time.Duration]{},
+	}
+
+	// Add --[no-]collector.<name>, --collector.<name>.interval, and --collector.<name>.timeout
+	// flags for every available collector.
+	defaults := collector.DefaultCollectors()
+	for _, name := range slices.Sorted(maps.Keys(defaults)) {
+		state := "disabled"
+		if defaults[name] {
+			state = "enabled"
+		}
+		f.collectorsEnabled[name] = newFlag(app,
+			"collector."+name,
+			fmt.Sprintf("Enable the %s collector (default: %s).", name, state),
+			"", strconv.FormatBool(defaults[name]), (*kingpin.FlagClause).Bool)
+		f.collectorsIntervals[name] = newFlag(app,
+			"collector."+name+".interval",
+			fmt.Sprintf("How often to refresh the %s collector. If 0, --refresh-interval is used.", name),
+			"", "0s", (*kingpin.FlagClause).Duration)
+		f.collectorsTimeouts[name] = newFlag(app,
+			"collector."+name+".timeout",
+			fmt.Sprintf("How long the %s collector's queries may run. If 0, --query-timeout is used.", name),
+			"", "0s", (*kingpin.FlagClause).Duration)
+	}
+
+	return f
+}
+
+// loadConfig builds the config from the config file, if any, overridden by the flags.
+// The returned config is not validated.
+func (f *configFlags) loadConfig(configFile string) (*collector.Config, error) {
+	c := &collector.Config{}
+	keys := map[string]any{}
+	if configFile != "" {
+		var err error
+		if c, err = collector.LoadConfig(configFile); err != nil {
+			return nil, err
+		}
+		if keys, err = configKeys(configFile); err != nil {
+			return nil, err
+		}
+	}
+	inFile := func(key string) bool {
+		_, ok := keys[key]
+		return ok
+	}
+
+	f.account.override(&c.AccountName, inFile("account_name"))
+	f.username.override(&c.Username, inFile("username"))
+	f.password.override(&c.Password, inFile("password"))
+	f.privateKeyPath.override(&c.PrivateKeyPath, inFile("private_key_path"))
+	f.privateKeyPassword.override(&c.PrivateKeyPassword, inFile("private_key_password"))
+	f.role.override(&c.Role, inFile("role"))
+	f.warehouse.override(&c.Warehouse, inFile("warehouse"))
+	f.excludeDeleted.override(&c.ExcludeDeleted, inFile("exclude_deleted_tables"))
+	f.enableTracing.override(&c.EnableTracing, inFile("enable_tracing"))
+	f.refreshInterval.override(&c.RefreshInterval, inFile("refresh_interval"))
+	f.queryTimeout.override(&c.QueryTimeout, inFile("query_timeout"))
+	f.maxOpenConns.override(&c.MaxOpenConns, inFile("max_open_connections"))
+	f.maxIdleConns.override(&c.MaxIdleConns, inFile("max_idle_connections"))
+	f.connMaxLifetime.override(&c.ConnMaxLifetime, inFile("connection_max_lifetime"))
+	f.sessionKeepAlive.override(&c.ClientSessionKeepAlive, inFile("client_session_keep_alive"))
+
+	// Collector flags only override the config file when given, since collectors
+	// missing from the config already use their defaults.
+	for name, flag := range f.collectorsEnabled {
+		if flag.given() {
+			c.Collectors = setKey(c.Collectors, name, *flag.value)
+		}
+	}
+	for name, flag := range f.collectorsIntervals {
+		if flag.given() {
+			c.CollectorIntervals = setKey(c.CollectorIntervals, name, *flag.value)
+		}
+	}
+	for name, flag := range f.collectorsTimeouts {
+		if flag.given() {
+			c.CollectorTimeouts = setKey(c.CollectorTimeouts, name, *flag.value)
+		}
+	}
+
+	if f.queryHistoryLabels.given() {
+		c.QueryHistoryLabels = nil
+		for _, label := range strings.Split(*f.queryHistoryLabels.value, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				c.QueryHistoryLabels = append(c.QueryHistoryLabels, label)
+			}
+		}
+	}
+
+	if f.queryLatencyBuckets.given() {
+		c.QueryLatencyBuckets = nil
+		for _, bucket := range strings.Split(*f.queryLatencyBuckets.value, ",") {
+			if bucket = strings.TrimSpace(bucket); bucket == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(bucket, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query latency bucket %q: %w", bucket, err)
+			}
+			c.QueryLatencyBuckets = append(c.QueryLatencyBuckets, value)
+		}
+	}
+
+	if f.customQueriesFile.given() {
+		queries, err := collector.LoadCustomQueries(*f.customQueriesFile.value)
+		if err != nil {
+			return nil, err
+		}
+		c.CustomQueries = queries
+	}
+
+	return c, nil
+}
+
+// configKeys returns the top-level keys set by the config file, so that flag defaults do not
+// override values explicitly set to zero, such as query_timeout: 0.
+func configKeys(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	keys := map[string]any{}
+	if err := yaml.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// setKey sets the key of the map to the value, creating the map if it is nil.
+func setKey[T any](m map[string]T, key string, value T) map[string]T {
+	if m == nil {
+		m = map[string]T{}
+	}
+	m[key] = value
+	return m
+}
@@ -0,0 +1,97 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFlags_loadConfig(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		args                 []string
+		env                  string
+		file                 string
+		expectedQueryTimeout time.Duration
+		expectedRole         string
+	}{
+		{
+			name:                 "Defaults",
+			expectedQueryTimeout: 0,
+			expectedRole:         "ACCOUNTADMIN",
+		},
+		{
+			name:                 "File without the keys",
+			file:                 "account_name: some-account\n",
+			expectedQueryTimeout: 0,
+			expectedRole:         "ACCOUNTADMIN",
+		},
+		{
+			name:                 "File",
+			file:                 "query_timeout: 1m\nrole: SYSADMIN\n",
+			expectedQueryTimeout: time.Minute,
+			expectedRole:         "SYSADMIN",
+		},
+		{
+			name:                 "File with zero values",
+			file:                 "query_timeout: 0s\nrole: \"\"\n",
+			expectedQueryTimeout: 0,
+			expectedRole:         "",
+		},
+		{
+			name:                 "Environment over file",
+			env:                  "2m",
+			file:                 "query_timeout: 1m\n",
+			expectedQueryTimeout: 2 * time.Minute,
+			expectedRole:         "ACCOUNTADMIN",
+		},
+		{
+			name:                 "Flag over environment and file",
+			args:                 []string{"--query-timeout=3m", "--role=PUBLIC"},
+			env:                  "2m",
+			file:                 "query_timeout: 1m\nrole: SYSADMIN\n",
+			expectedQueryTimeout: 3 * time.Minute,
+			expectedRole:         "PUBLIC",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SNOWFLAKE_EXPORTER_QUERY_TIMEOUT", tc.env)
+
+			app := kingpin.New("test", "")
+			f := addConfigFlags(app)
+			_, err := app.Parse(tc.args)
+			require.NoError(t, err)
+
+			configFile := ""
+			if tc.file != "" {
+				configFile = filepath.Join(t.TempDir(), "config.yml")
+				require.NoError(t, os.WriteFile(configFile, []byte(tc.file), 0o600))
+			}
+
+			c, err := f.loadConfig(configFile)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedQueryTimeout, c.QueryTimeout)
+			require.Equal(t, tc.expectedRole, c.Role)
+		})
+	}
+}
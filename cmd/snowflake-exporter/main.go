@@ -16,13 +16,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
-	"github.com/grafana/snowflake-prometheus-exporter/collector"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -34,17 +36,10 @@ import (
 )
 
 var (
-	webConfig          = webflag.AddFlags(kingpin.CommandLine, ":9975")
-	metricPath         = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").Envar("SNOWFLAKE_EXPORTER_WEB_TELEMETRY_PATH").String()
-	account            = kingpin.Flag("account", "The account to collect metrics for.").Envar("SNOWFLAKE_EXPORTER_ACCOUNT").Required().String()
-	username           = kingpin.Flag("username", "The username for the user used when querying metrics.").Envar("SNOWFLAKE_EXPORTER_USERNAME").Required().String()
-	password           = kingpin.Flag("password", "The password for the user used when querying metrics.").Envar("SNOWFLAKE_EXPORTER_PASSWORD").String()
-	privateKeyPath     = kingpin.Flag("private-key-path", "The path to the user's RSA private key").Envar("SNOWFLAKE_EXPORTER_PRIVATE_KEY_PATH").String()
-	privateKeyPassword = kingpin.Flag("private-key-password", "The password for the user's RSA private key.").Envar("SNOWFLAKE_EXPORTER_PRIVATE_KEY_PASSWORD").String()
-	role               = kingpin.Flag("role", "The role to use when querying metrics.").Default("ACCOUNTADMIN").Envar("SNOWFLAKE_EXPORTER_ROLE").String()
-	warehouse          = kingpin.Flag("warehouse", "The warehouse to use when querying metrics.").Envar("SNOWFLAKE_EXPORTER_WAREHOUSE").Required().String()
-	excludeDeleted     = kingpin.Flag("exclude-deleted-tables", "Exclude deleted tables when collecting table storage metrics.").Default("false").Bool()
-	enableTracing      = kingpin.Flag("enable-tracing", "Enable trace logging for Snowflake connections.").Default("false").Envar("SNOWFLAKE_EXPORTER_ENABLE_TRACING").Bool()
+	webConfig       = webflag.AddFlags(kingpin.CommandLine, ":9975")
+	metricPath      = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").Envar("SNOWFLAKE_EXPORTER_WEB_TELEMETRY_PATH").String()
+	enableLifecycle = kingpin.Flag("web.enable-lifecycle", "Enable reloading the configuration through HTTP requests to /-/reload.").Default("false").Bool()
+	configFile      = kingpin.Flag("config.file", "Path to a YAML configuration file. Flags given on the command line or through environment variables override its values.").Envar("SNOWFLAKE_EXPORTER_CONFIG_FILE").String()
 )
 
 const (
@@ -57,11 +52,16 @@ const (
 		<p><a href='%s'>Metrics</a></p>
 	</body>
 </html>`
+
+	// Subtracted from Prometheus' scrape timeout, to leave time to respond before the scrape times out.
+	scrapeTimeoutOffset = 500 * time.Millisecond
 )
 
 func main() {
 	kingpin.Version(version.Print(exporterName))
 
+	cfgFlags := addConfigFlags(kingpin.CommandLine)
+
 	promslogConfig := &promslog.Config{}
 
 	promslogflag.AddFlags(kingpin.CommandLine, promslogConfig)
@@ -70,16 +70,10 @@ func main() {
 
 	logger := promslog.New(promslogConfig)
 
-	c := &collector.Config{
-		AccountName:        *account,
-		Username:           *username,
-		Password:           *password,
-		PrivateKeyPath:     *privateKeyPath,
-		PrivateKeyPassword: *privateKeyPassword,
-		Role:               *role,
-		Warehouse:          *warehouse,
-		ExcludeDeleted:     *excludeDeleted,
-		EnableTracing:      *enableTracing,
+	c, err := cfgFlags.loadConfig(*configFile)
+	if err != nil {
+		logger.Error("Failed to load configuration.", "err", err)
+		os.Exit(1)
 	}
 
 	if err := c.Validate(); err != nil {
@@ -89,21 +83,78 @@ func main() {
 
 	// Add component prefix to logger for better log correlation
 	collectorLogger := logger.With("component", "snowflake-exporter")
-	col := collector.NewCollector(collectorLogger, c)
 
-	// Register collector with prometheus client library
-	prometheus.MustRegister(col)
+	// Without any account, the exporter only serves probes of the accounts given as targets.
+	cols := newAccountCollectors(collectorLogger, c)
+	probes := newProbeCollectors(collectorLogger, c)
+
+	// Reload the configuration on SIGHUP, and on POST /-/reload if enabled
+	reloader := newReloader(logger, cfgFlags, *configFile, cols, probes)
+	prometheus.MustRegister(reloader)
+	go reloader.watchSignals()
 
 	// Add build-info collector
 	prometheus.MustRegister(collectors.NewBuildInfoCollector())
 
-	serveMetrics(logger)
+	serveMetrics(logger, cols, probes, reloader)
+}
+
+// metricsHandler returns a handler which serves the metrics of the default registry and the collectors
+// of all accounts. Queries run during the scrape are cancelled shortly before Prometheus' scrape timeout.
+func metricsHandler(logger *slog.Logger, cols *accountCollectors) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if timeout, ok := scrapeTimeout(r); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		registry := prometheus.NewRegistry()
+		for _, col := range cols.collectors() {
+			if err := registry.Register(col.WithContext(ctx)); err != nil {
+				logger.Error("Failed to register collector.", "err", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, registry}
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+			ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		}).ServeHTTP(w, r)
+	})
 }
 
-func serveMetrics(logger *slog.Logger) {
+// scrapeTimeout returns the timeout of the scrape, as advertised by Prometheus, less scrapeTimeoutOffset.
+func scrapeTimeout(r *http.Request) (time.Duration, bool) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+	timeout := time.Duration(seconds*float64(time.Second)) - scrapeTimeoutOffset
+	if timeout <= 0 {
+		return 0, false
+	}
+	return timeout, true
+}
+
+func serveMetrics(logger *slog.Logger, cols *accountCollectors, probes *probeCollectors, reloader *reloader) {
 	landingPage := []byte(fmt.Sprintf(landingPageHTML, *metricPath))
 
-	http.Handle(*metricPath, promhttp.Handler())
+	http.Handle(*metricPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, metricsHandler(logger, cols)))
+	http.Handle("/probe", probeHandler(logger, probes))
+	if *enableLifecycle {
+		http.Handle("/-/reload", reloader)
+	} else {
+		http.HandleFunc("/-/reload", func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "Lifecycle API is not enabled.", http.StatusForbidden)
+		})
+	}
 	http.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=UTF-8") // nolint: errcheck
 		w.Write(landingPage)                                       // nolint: errcheck
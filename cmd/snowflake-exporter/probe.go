@@ -0,0 +1,138 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/grafana/snowflake-prometheus-exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeKey identifies the collector of the probes of a target with a module.
+type probeKey struct {
+	target string
+	module string
+}
+
+// probeCollectors holds a collector for each probed target and module, so that probes reuse the
+// collector's connections to Snowflake instead of logging in on every request.
+type probeCollectors struct {
+	mtx    sync.Mutex
+	logger *slog.Logger
+	config *collector.Config
+	cols   map[probeKey]*collector.Collector
+}
+
+// newProbeCollectors creates the probe collectors of the config. Collectors are created on the
+// first probe of each target and module.
+func newProbeCollectors(logger *slog.Logger, c *collector.Config) *probeCollectors {
+	return &probeCollectors{
+		logger: logger,
+		config: c,
+		cols:   map[probeKey]*collector.Collector{},
+	}
+}
+
+// get returns the collector of the target and module, creating it if it does not exist yet.
+// It returns an error if the target cannot be probed with the module.
+func (p *probeCollectors) get(target, module string) (*collector.Collector, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	key := probeKey{target: target, module: module}
+	if col, ok := p.cols[key]; ok {
+		return col, nil
+	}
+
+	c, err := p.config.ProbeConfig(target, module)
+	if err != nil {
+		return nil, err
+	}
+	col := collector.NewCollector(p.logger.With("target", target, "module", module), c)
+	p.cols[key] = col
+	return col, nil
+}
+
+// release closes the collector if it was removed by a reload while it was in use.
+func (p *probeCollectors) release(target, module string, col *collector.Collector) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.cols[probeKey{target: target, module: module}] != col {
+		_ = col.Close()
+	}
+}
+
+// update replaces the config of the probe collectors. Collectors whose target can no longer be
+// probed with their module, for example because the module was removed, are closed.
+// The config is assumed to be valid.
+func (p *probeCollectors) update(c *collector.Config) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.config = c
+	for key, col := range p.cols {
+		probe, err := c.ProbeConfig(key.target, key.module)
+		if err != nil {
+			p.logger.Info("Closing collector of removed probe.", "target", key.target, "module", key.module, "err", err)
+			_ = col.Close()
+			delete(p.cols, key)
+			continue
+		}
+		col.SetConfig(probe)
+	}
+}
+
+// probeHandler returns a handler which collects the metrics of the account given by the target
+// parameter, using the credentials of the module given by the module parameter.
+// Snowflake is queried on every probe, through connections kept open between probes.
+func probeHandler(logger *slog.Logger, probes *probeCollectors) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		target := params.Get("target")
+		module := params.Get("module")
+
+		col, err := probes.get(target, module)
+		if err != nil {
+			logger.Debug("Invalid probe request.", "target", target, "module", module, "err", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer probes.release(target, module, col)
+
+		ctx := r.Context()
+		if timeout, ok := scrapeTimeout(r); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(col.WithContext(ctx)); err != nil {
+			logger.Error("Failed to register collector.", "target", target, "module", module, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		}).ServeHTTP(w, r)
+	})
+}
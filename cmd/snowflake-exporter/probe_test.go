@@ -0,0 +1,79 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/grafana/snowflake-prometheus-exporter/collector"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeCollectors(t *testing.T) {
+	config := &collector.Config{
+		Warehouse: "ACCOUNT_WH",
+		Role:      "ACCOUNTADMIN",
+		Modules: map[string]collector.Module{
+			"monitoring": {Username: "some_user", Password: "some_pass"},
+			"reader":     {Username: "other_user", Password: "other_pass"},
+		},
+	}
+	probes := newProbeCollectors(promslog.NewNopLogger(), config)
+
+	t.Run("Collectors are reused", func(t *testing.T) {
+		col, err := probes.get("some-account", "monitoring")
+		require.NoError(t, err)
+		again, err := probes.get("some-account", "monitoring")
+		require.NoError(t, err)
+		require.Same(t, col, again)
+
+		other, err := probes.get("other-account", "monitoring")
+		require.NoError(t, err)
+		require.NotSame(t, col, other)
+	})
+
+	t.Run("Invalid probes are not kept", func(t *testing.T) {
+		_, err := probes.get("some-account", "unknown")
+		require.Error(t, err)
+		_, err = probes.get("", "monitoring")
+		require.Error(t, err)
+		require.Len(t, probes.cols, 2)
+	})
+
+	t.Run("Reload drops collectors of removed modules", func(t *testing.T) {
+		reader, err := probes.get("some-account", "reader")
+		require.NoError(t, err)
+		monitoring, err := probes.get("some-account", "monitoring")
+		require.NoError(t, err)
+
+		reloaded := *config
+		reloaded.Modules = map[string]collector.Module{
+			"monitoring": {Username: "some_user", Password: "new_pass"},
+		}
+		probes.update(&reloaded)
+
+		require.Len(t, probes.cols, 2)
+		require.NotContains(t, probes.cols, probeKey{target: "some-account", module: "reader"})
+		again, err := probes.get("some-account", "monitoring")
+		require.NoError(t, err)
+		require.Same(t, monitoring, again)
+
+		_, err = probes.get("some-account", "reader")
+		require.Error(t, err)
+		// A probe in progress during the reload closes its collector once done
+		probes.release("some-account", "reader", reader)
+	})
+}
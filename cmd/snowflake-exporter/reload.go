@@ -0,0 +1,125 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reloader re-reads the configuration from the config file and flags, and applies it to the collectors.
+type reloader struct {
+	// mtx serializes reloads
+	mtx        sync.Mutex
+	logger     *slog.Logger
+	flags      *configFlags
+	configFile string
+	cols       *accountCollectors
+	probes     *probeCollectors
+
+	lastReloadSuccessful       prometheus.Gauge
+	lastReloadSuccessTimestamp prometheus.Gauge
+}
+
+// newReloader creates a reloader of the collectors, whose config was loaded successfully.
+func newReloader(logger *slog.Logger, flags *configFlags, configFile string, cols *accountCollectors, probes *probeCollectors) *reloader {
+	r := &reloader{
+		logger:     logger,
+		flags:      flags,
+		configFile: configFile,
+		cols:       cols,
+		probes:     probes,
+		lastReloadSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "snowflake",
+			Subsystem: "exporter",
+			Name:      "config_last_reload_successful",
+			Help:      "Whether the last configuration reload attempt was successful.",
+		}),
+		lastReloadSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "snowflake",
+			Subsystem: "exporter",
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful configuration reload.",
+		}),
+	}
+	r.lastReloadSuccessful.Set(1)
+	r.lastReloadSuccessTimestamp.SetToCurrentTime()
+	return r
+}
+
+// Describe implements prometheus.Collector.
+func (r *reloader) Describe(descs chan<- *prometheus.Desc) {
+	r.lastReloadSuccessful.Describe(descs)
+	r.lastReloadSuccessTimestamp.Describe(descs)
+}
+
+// Collect implements prometheus.Collector.
+func (r *reloader) Collect(metrics chan<- prometheus.Metric) {
+	r.lastReloadSuccessful.Collect(metrics)
+	r.lastReloadSuccessTimestamp.Collect(metrics)
+}
+
+// reload loads and validates the configuration, and replaces the collector's config with it.
+// If the configuration cannot be loaded or is invalid, the collectors keep their current config.
+func (r *reloader) reload() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	c, err := r.flags.loadConfig(r.configFile)
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		r.lastReloadSuccessful.Set(0)
+		r.logger.Error("Failed to reload configuration.", "err", err)
+		return err
+	}
+
+	r.cols.update(c)
+	r.probes.update(c)
+	r.lastReloadSuccessful.Set(1)
+	r.lastReloadSuccessTimestamp.SetToCurrentTime()
+	r.logger.Info("Reloaded configuration.")
+	return nil
+}
+
+// watchSignals reloads the configuration whenever the process receives SIGHUP.
+func (r *reloader) watchSignals() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		_ = r.reload()
+	}
+}
+
+// ServeHTTP reloads the configuration on POST requests.
+func (r *reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "This endpoint requires a POST request.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.reload(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+	}
+}
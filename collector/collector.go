@@ -17,11 +17,16 @@
 package collector
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	_ "github.com/snowflakedb/gosnowflake/v2" // Import the snowflake DB driver
@@ -43,8 +48,156 @@ const (
 	labelSchemaName    = "schema_name"
 	labelSchemaID      = "schema_id"
 	labelSize          = "size"
+	labelCollector     = "collector"
+	labelAccount       = "account"
+
+	labelWarehouseName   = "warehouse_name"
+	labelUserName        = "user_name"
+	labelRoleName        = "role_name"
+	labelQueryType       = "query_type"
+	labelExecutionStatus = "execution_status"
+	labelErrorCode       = "error_code"
+	labelTaskName        = "task_name"
+	labelState           = "state"
+	labelPipeName        = "pipe_name"
+	labelSourceCloud     = "source_cloud"
+	labelSourceRegion    = "source_region"
+	labelTargetCloud     = "target_cloud"
+	labelTargetRegion    = "target_region"
+	labelTransferType    = "transfer_type"
+	labelEventName       = "event_name"
+	labelAction          = "action"
+	labelThreshold       = "threshold"
+	labelAccountName     = "account_name"
+	labelUsageType       = "usage_type"
+	labelCurrency        = "currency"
+	labelContractNumber  = "contract_number"
+	labelBalanceType     = "balance_type"
 )
 
+// Names of the collectors which can be enabled or disabled through the config.
+const (
+	collectorStorage             = "storage"
+	collectorDatabaseStorage     = "database_storage"
+	collectorCredits             = "credits"
+	collectorWarehouseCredits    = "warehouse_credits"
+	collectorLogins              = "logins"
+	collectorWarehouseLoad       = "warehouse_load"
+	collectorAutoClustering      = "auto_clustering"
+	collectorTableStorage        = "table_storage"
+	collectorDeletedTables       = "deleted_tables"
+	collectorReplication         = "replication"
+	collectorQueryHistory        = "query_history"
+	collectorQueryLatency        = "query_latency"
+	collectorQueryEfficiency     = "query_efficiency"
+	collectorTasks               = "tasks"
+	collectorServerlessTasks     = "serverless_tasks"
+	collectorPipeUsage           = "pipe_usage"
+	collectorCopyHistory         = "copy_history"
+	collectorMaterializedViews   = "materialized_views"
+	collectorSearchOptimization  = "search_optimization"
+	collectorDynamicTables       = "dynamic_tables"
+	collectorDataTransfer        = "data_transfer"
+	collectorWarehouseEvents     = "warehouse_events"
+	collectorWarehouseState      = "warehouse_state"
+	collectorResourceMonitors    = "resource_monitors"
+	collectorOrganizationUsage   = "organization_usage"
+	collectorOrganizationBalance = "organization_balance"
+)
+
+// collectorDefaults holds whether each collector is enabled when not configured otherwise.
+var collectorDefaults = map[string]bool{
+	collectorStorage:             true,
+	collectorDatabaseStorage:     true,
+	collectorCredits:             true,
+	collectorWarehouseCredits:    true,
+	collectorLogins:              true,
+	collectorWarehouseLoad:       true,
+	collectorAutoClustering:      true,
+	collectorTableStorage:        true,
+	collectorDeletedTables:       true,
+	collectorReplication:         true,
+	collectorQueryHistory:        false,
+	collectorQueryLatency:        false,
+	collectorQueryEfficiency:     false,
+	collectorTasks:               false,
+	collectorServerlessTasks:     false,
+	collectorPipeUsage:           false,
+	collectorCopyHistory:         false,
+	collectorMaterializedViews:   false,
+	collectorSearchOptimization:  false,
+	collectorDynamicTables:       false,
+	collectorDataTransfer:        false,
+	collectorWarehouseEvents:     false,
+	collectorWarehouseState:      false,
+	collectorResourceMonitors:    false,
+	collectorOrganizationUsage:   false,
+	collectorOrganizationBalance: false,
+}
+
+// defaultQueryLatencyBuckets are the upper bounds in seconds of the query latency histograms' buckets,
+// if none are configured.
+var defaultQueryLatencyBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// warehouseStates are the states of a warehouse reported by SHOW WAREHOUSES.
+var warehouseStates = []string{"STARTED", "SUSPENDED", "RESIZING"}
+
+// balanceTypes are the balances of REMAINING_BALANCE_DAILY, in the order they are queried.
+var balanceTypes = []string{"capacity", "free_usage", "rollover", "on_demand_consumption"}
+
+// queryLatencyColumns are the QUERY_HISTORY columns, in milliseconds, whose distribution is reported by the
+// query_latency collector.
+var queryLatencyColumns = []string{"TOTAL_ELAPSED_TIME", "QUEUED_OVERLOAD_TIME", "COMPILATION_TIME"}
+
+// queryHistoryDimension is a label by which query history metrics can be split, along with the
+// QUERY_HISTORY column it is taken from.
+type queryHistoryDimension struct {
+	label  string
+	column string
+}
+
+// queryHistoryDimensions are the dimensions of query history metrics, in the order of the descriptors' labels.
+var queryHistoryDimensions = []queryHistoryDimension{
+	{labelWarehouseName, "WAREHOUSE_NAME"},
+	{labelUserName, "USER_NAME"},
+	{labelRoleName, "ROLE_NAME"},
+	{labelQueryType, "QUERY_TYPE"},
+	{labelExecutionStatus, "EXECUTION_STATUS"},
+}
+
+// DefaultCollectors returns the names of all available collectors, mapped to whether
+// they are enabled by default.
+func DefaultCollectors() map[string]bool {
+	defaults := make(map[string]bool, len(collectorDefaults))
+	for name, enabled := range collectorDefaults {
+		defaults[name] = enabled
+	}
+	return defaults
+}
+
+// scraper queries a single group of related metrics from Snowflake.
+type scraper struct {
+	name  string
+	descs []*prometheus.Desc
+	// interval overrides how often the scraper is refreshed, if non-zero.
+	interval time.Duration
+	// scrape emits the metrics through the provided channel, and returns the number of rows queried.
+	scrape func(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error)
+}
+
+// scrapeResult holds the outcome of the most recent run of a scraper.
+type scrapeResult struct {
+	metrics  []prometheus.Metric
+	success  bool
+	duration time.Duration
+	rows     int
+	// time is when the metrics were collected. After a failure, the metrics and time of the previous
+	// run are kept.
+	time time.Time
+	// attempt is when the collector was last run, successfully or not.
+	attempt time.Time
+}
+
 // openSnowflakeDatabase opens a connection to a Snowflake database using the given connection string.
 func openSnowflakeDatabase(connStr string) (*sql.DB, error) {
 	return sql.Open("snowflake", connStr)
@@ -52,10 +205,29 @@ func openSnowflakeDatabase(connStr string) (*sql.DB, error) {
 
 // Collector is a prometheus.Collector that retrieves metrics for a Snowflake account.
 type Collector struct {
-	config *Config
-	logger *slog.Logger
+	settings atomic.Pointer[settings]
+	// configChanged is signalled when the config is replaced, to wake up Run.
+	configChanged chan struct{}
+	// constLabels are added to every metric of the collector.
+	constLabels prometheus.Labels
+	logger      *slog.Logger
 	// For mocking
 	openDatabase func(string) (*sql.DB, error)
+	now          func() time.Time
+
+	scrapers []scraper
+
+	// refreshMtx serializes refreshes, so collectors are not run concurrently with themselves.
+	refreshMtx sync.Mutex
+	// dbMtx guards the connection pool to Snowflake, which is kept open across refreshes.
+	dbMtx sync.Mutex
+	db    *sql.DB
+	// dbSettings are the connection settings db was opened with.
+	dbSettings connectionSettings
+
+	// cacheMtx guards the results of the most recent run of each collector, which are served on scrape.
+	cacheMtx sync.RWMutex
+	results  map[string]scrapeResult
 
 	storageBytes                      *prometheus.Desc
 	stageBytes                        *prometheus.Desc
@@ -83,361 +255,1062 @@ type Collector struct {
 	tableDeletedTables                *prometheus.Desc
 	replicationUsedCredits            *prometheus.Desc
 	replicationTransferredBytes       *prometheus.Desc
+	queryElapsedSeconds               *prometheus.Desc
+	queryQueuedOverloadSeconds        *prometheus.Desc
+	queryCompilationLatencySeconds    *prometheus.Desc
+	querySpilledLocalBytes            *prometheus.Desc
+	querySpilledRemoteBytes           *prometheus.Desc
+	queryScannedBytes                 *prometheus.Desc
+	queryScannedPartitions            *prometheus.Desc
+	queryTotalPartitions              *prometheus.Desc
+	taskRuns                          *prometheus.Desc
+	taskLastRunDurationSeconds        *prometheus.Desc
+	taskLastSuccessTimestamp          *prometheus.Desc
+	serverlessTaskCredits             *prometheus.Desc
+	pipeCredits                       *prometheus.Desc
+	pipeInsertedBytes                 *prometheus.Desc
+	pipeInsertedFiles                 *prometheus.Desc
+	copyErrors                        *prometheus.Desc
+	copyLastLoadTimestamp             *prometheus.Desc
+	materializedViewCredits           *prometheus.Desc
+	searchOptimizationCredits         *prometheus.Desc
+	dynamicTableRefreshes             *prometheus.Desc
+	dynamicTableLastRefreshSeconds    *prometheus.Desc
+	dynamicTableLagSeconds            *prometheus.Desc
+	dynamicTableTargetLagSeconds      *prometheus.Desc
+	dynamicTableLastSuccessTimestamp  *prometheus.Desc
+	dataTransferBytes                 *prometheus.Desc
+	warehouseEvents                   *prometheus.Desc
+	warehouseObservedClusters         *prometheus.Desc
+	warehouseState                    *prometheus.Desc
+	warehouseMinClusters              *prometheus.Desc
+	warehouseMaxClusters              *prometheus.Desc
+	warehouseStartedClusters          *prometheus.Desc
+	warehouseRunningQueries           *prometheus.Desc
+	warehouseQueuedQueries            *prometheus.Desc
+	warehouseAutoSuspendSeconds       *prometheus.Desc
+	warehouseAutoResume               *prometheus.Desc
+	resourceMonitorCreditQuota        *prometheus.Desc
+	resourceMonitorUsedCredits        *prometheus.Desc
+	resourceMonitorRemainingCredits   *prometheus.Desc
+	resourceMonitorThresholdPercent   *prometheus.Desc
+	resourceMonitorWarehouse          *prometheus.Desc
+	organizationUsageInCurrency       *prometheus.Desc
+	organizationEffectiveRate         *prometheus.Desc
+	organizationRemainingBalance      *prometheus.Desc
 	up                                *prometheus.Desc
+	cacheAge                          *prometheus.Desc
+	collectorSuccess                  *prometheus.Desc
+	collectorDuration                 *prometheus.Desc
+	collectorRows                     *prometheus.Desc
 }
 
 // NewCollector creates a new collector from a given config.
 // The config is assumed to be valid.
 func NewCollector(logger *slog.Logger, c *Config) *Collector {
-	return &Collector{
-		config:       c,
-		logger:       logger,
-		openDatabase: openSnowflakeDatabase,
+	return newCollector(logger, c, nil)
+}
+
+// NewAccountCollector creates a new collector from a given config, whose metrics are labeled
+// with the name of the account, so that the metrics of several accounts can be served together.
+// The config is assumed to be valid.
+func NewAccountCollector(logger *slog.Logger, c *Config) *Collector {
+	return newCollector(logger, c, prometheus.Labels{labelAccount: c.AccountName})
+}
+
+// newCollector creates a new collector whose metrics all have the given constant labels.
+func newCollector(logger *slog.Logger, c *Config, constLabels prometheus.Labels) *Collector {
+	col := &Collector{
+		constLabels:   constLabels,
+		configChanged: make(chan struct{}, 1),
+		logger:        logger,
+		openDatabase:  openSnowflakeDatabase,
+		now:           time.Now,
+		results:       map[string]scrapeResult{},
 		storageBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "storage_bytes"),
 			"Number of bytes of table storage used, including bytes for data currently in Time Travel.",
 			nil,
-			nil,
+			constLabels,
 		),
 		stageBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "stage_bytes"),
 			"Number of bytes of stage storage used by files in all internal stages (named, table, and user).",
 			nil,
-			nil,
+			constLabels,
 		),
 		failsafeBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "failsafe_bytes"),
 			"Number of bytes of data in Fail-safe.",
 			nil,
-			nil,
+			constLabels,
 		),
 		databaseBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "database", "bytes"),
 			"Average number of bytes of database storage used, including data in Time Travel.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		databaseFailsafeBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "database", "failsafe_bytes"),
 			"Average number of bytes of Fail-safe storage used.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		usedComputeCredits: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "used_compute_credits"),
 			"Average overall credits billed per hour for virtual warehouses over the last 24 hours.",
 			[]string{labelServiceType, labelService},
-			nil,
+			constLabels,
 		),
 		usedCloudServicesCredits: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "used_cloud_services_credits"),
 			"Average overall credits billed per hour for cloud services over the last 24 hours.",
 			[]string{labelServiceType, labelService},
-			nil,
+			constLabels,
 		),
 		warehouseUsedComputeCredits: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "warehouse", "used_compute_credits"),
 			"Average overall credits billed per hour for the warehouse over the last 24 hours.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		warehouseUsedCloudServicesCredits: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "warehouse", "used_cloud_service_credits"),
 			"Average overall credits billed per hour for cloud services for the warehouse over the last 24 hours.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		logins: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "login_rate"),
 			"Rate of logins per-hour over the last 24 hours.",
 			[]string{labelClientType, labelClientVersion},
-			nil,
+			constLabels,
 		),
 		successfulLogins: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "successful_login_rate"),
 			"Rate of successful logins per-hour over the last 24 hours.",
 			[]string{labelClientType, labelClientVersion},
-			nil,
+			constLabels,
 		),
 		failedLogins: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "failed_login_rate"),
 			"Rate of failed logins per-hour over the last 24 hours.",
 			[]string{labelClientType, labelClientVersion},
-			nil,
+			constLabels,
 		),
 		warehouseExecutedQueryLoad: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "warehouse", "executed_queries"),
 			"Average query load for queries executed over the last 24 hours.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		warehouseOverloadedQueueLoad: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "warehouse", "overloaded_queue_size"),
 			"Average load value for queries queued because the warehouse was being overloaded over the last 24 hours.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		warehouseProvisioningQueueLoad: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "warehouse", "provisioning_queue_size"),
 			"Average load value for queries queued because the warehouse was being provisioned over the last 24 hours.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		warehouseBlockedQueryLoad: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "warehouse", "blocked_queries"),
 			"Average load value for queries blocked by a transaction lock over the last 24 hours.",
 			[]string{labelName, labelID},
-			nil,
+			constLabels,
 		),
 		autoClusteringCredits: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "auto_clustering", "credits"),
 			"Sum of the number of credits billed for automatic reclustering over the last 24 hours.",
 			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		autoClusteringBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "auto_clustering", "bytes"),
 			"Sum of the number of bytes reclustered during automatic reclustering over the last 24 hours.",
 			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		autoClusteringRows: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "auto_clustering", "rows"),
 			"Sum of the number of rows clustered during automatic reclustering over the last 24 hours.",
 			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		tableActiveBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "table", "active_bytes"),
 			"Sum of active bytes owned by the table.",
 			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		tableTimeTravelBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "table", "time_travel_bytes"),
 			"Sum of bytes in Time Travel state owned by the table.",
 			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		tableFailsafeBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "table", "failsafe_bytes"),
 			"Sum of bytes in Fail-Safe state owned by the table.",
 			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		tableCloneBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "table", "clone_bytes"),
 			"Sum of bytes owned by the table that are retained after deletion because they are referenced by one or more clones.",
 			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		tableDeletedTables: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "table", "deleted_tables"),
 			"Number of tables that have been purged from storage.",
 			nil,
-			nil,
+			constLabels,
 		),
 		replicationUsedCredits: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "db_replication", "used_credits"),
 			"Sum of the number of credits used for database replication over the last 24 hours.",
 			[]string{labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
 		),
 		replicationTransferredBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "db_replication", "transferred_bytes"),
 			"Sum of the number of transferred bytes for database replication over the last 24 hours.",
 			[]string{labelDatabaseName, labelDatabaseID},
-			nil,
+			constLabels,
+		),
+		queryElapsedSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query_latency", "total_elapsed_seconds"),
+			"Distribution of the elapsed time of queries started on the warehouse over the last 24 hours.",
+			[]string{labelWarehouseName},
+			constLabels,
+		),
+		queryQueuedOverloadSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query_latency", "queued_overload_seconds"),
+			"Distribution of the time queries started on the warehouse over the last 24 hours spent queued because the warehouse was overloaded.",
+			[]string{labelWarehouseName},
+			constLabels,
+		),
+		queryCompilationLatencySeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query_latency", "compilation_seconds"),
+			"Distribution of the compilation time of queries started on the warehouse over the last 24 hours.",
+			[]string{labelWarehouseName},
+			constLabels,
+		),
+		querySpilledLocalBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query", "spilled_local_bytes"),
+			"Number of bytes spilled to local storage by queries started on the warehouse over the last 24 hours.",
+			[]string{labelWarehouseName, labelSize},
+			constLabels,
+		),
+		querySpilledRemoteBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query", "spilled_remote_bytes"),
+			"Number of bytes spilled to remote storage by queries started on the warehouse over the last 24 hours.",
+			[]string{labelWarehouseName, labelSize},
+			constLabels,
+		),
+		queryScannedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query", "scanned_bytes"),
+			"Number of bytes scanned by queries started on the warehouse over the last 24 hours.",
+			[]string{labelWarehouseName, labelSize},
+			constLabels,
+		),
+		queryScannedPartitions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query", "scanned_partitions"),
+			"Number of micro-partitions scanned by queries started on the warehouse over the last 24 hours.",
+			[]string{labelWarehouseName, labelSize},
+			constLabels,
+		),
+		queryTotalPartitions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query", "total_partitions"),
+			"Number of micro-partitions of the tables read by queries started on the warehouse over the last 24 hours.",
+			[]string{labelWarehouseName, labelSize},
+			constLabels,
+		),
+		taskRuns: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "task", "runs"),
+			"Number of runs of the task scheduled over the last 24 hours which ended in the state.",
+			[]string{labelTaskName, labelSchemaName, labelDatabaseName, labelState},
+			constLabels,
+		),
+		taskLastRunDurationSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "task", "last_run_duration_seconds"),
+			"Duration of the most recent run of the task completed over the last 7 days.",
+			[]string{labelTaskName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		taskLastSuccessTimestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "task", "last_success_timestamp_seconds"),
+			"Unix timestamp of the completion of the most recent successful run of the task over the last 7 days.",
+			[]string{labelTaskName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		serverlessTaskCredits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "serverless_task", "credits"),
+			"Sum of the number of credits billed for serverless runs of the task over the last 24 hours.",
+			[]string{labelTaskName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		pipeCredits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pipe", "credits"),
+			"Sum of the number of credits billed for loading data with the pipe over the last 24 hours.",
+			[]string{labelPipeName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		pipeInsertedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pipe", "inserted_bytes"),
+			"Number of bytes loaded by the pipe over the last 24 hours.",
+			[]string{labelPipeName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		pipeInsertedFiles: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pipe", "inserted_files"),
+			"Number of files loaded by the pipe over the last 24 hours.",
+			[]string{labelPipeName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		copyErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "copy", "errors"),
+			"Number of errors encountered loading files into the table over the last 24 hours.",
+			[]string{labelTableName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		copyLastLoadTimestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "copy", "last_load_timestamp_seconds"),
+			"Unix timestamp of the most recent load of a file into the table over the last 7 days.",
+			[]string{labelTableName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		materializedViewCredits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "materialized_view", "credits"),
+			"Sum of the number of credits billed for refreshing the materialized view over the last 24 hours.",
+			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
+			constLabels,
+		),
+		searchOptimizationCredits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "search_optimization", "credits"),
+			"Sum of the number of credits billed for maintaining the search access paths of the table over the last 24 hours.",
+			[]string{labelTableName, labelTableID, labelSchemaName, labelSchemaID, labelDatabaseName, labelDatabaseID},
+			constLabels,
+		),
+		dynamicTableRefreshes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dynamic_table", "refreshes"),
+			"Number of refreshes of the dynamic table over the last 24 hours which ended in the state.",
+			[]string{labelTableName, labelSchemaName, labelDatabaseName, labelState},
+			constLabels,
+		),
+		dynamicTableLastRefreshSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dynamic_table", "last_refresh_duration_seconds"),
+			"Duration of the most recent refresh of the dynamic table completed over the last 7 days.",
+			[]string{labelTableName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		dynamicTableLagSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dynamic_table", "lag_seconds"),
+			"Number of seconds between the data timestamp of the most recent successful refresh of the dynamic table and the current time.",
+			[]string{labelTableName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		dynamicTableTargetLagSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dynamic_table", "target_lag_seconds"),
+			"Target lag of the dynamic table, as of its most recent refresh.",
+			[]string{labelTableName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		dynamicTableLastSuccessTimestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dynamic_table", "last_success_timestamp_seconds"),
+			"Unix timestamp of the completion of the most recent successful refresh of the dynamic table over the last 7 days.",
+			[]string{labelTableName, labelSchemaName, labelDatabaseName},
+			constLabels,
+		),
+		dataTransferBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "data_transfer", "bytes"),
+			"Number of bytes transferred out of the account's region or cloud over the last 24 hours.",
+			[]string{labelSourceCloud, labelSourceRegion, labelTargetCloud, labelTargetRegion, labelTransferType},
+			constLabels,
+		),
+		warehouseEvents: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "events"),
+			"Number of events of the type completed by the warehouse over the last 24 hours.",
+			[]string{labelName, labelID, labelEventName},
+			constLabels,
+		),
+		warehouseObservedClusters: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "observed_clusters"),
+			"Maximum number of clusters of the warehouse observed in its events over the last 24 hours.",
+			[]string{labelName, labelID},
+			constLabels,
+		),
+		warehouseState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "state"),
+			"Whether the warehouse is currently in the state. 1 indicates the current state, 0 any other state.",
+			[]string{labelName, labelSize, labelState},
+			constLabels,
+		),
+		warehouseMinClusters: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "min_clusters"),
+			"Minimum number of clusters of the warehouse.",
+			[]string{labelName, labelSize},
+			constLabels,
+		),
+		warehouseMaxClusters: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "max_clusters"),
+			"Maximum number of clusters of the warehouse.",
+			[]string{labelName, labelSize},
+			constLabels,
+		),
+		warehouseStartedClusters: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "started_clusters"),
+			"Number of clusters of the warehouse currently started.",
+			[]string{labelName, labelSize},
+			constLabels,
+		),
+		warehouseRunningQueries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "running_queries"),
+			"Number of queries currently running on the warehouse.",
+			[]string{labelName, labelSize},
+			constLabels,
+		),
+		warehouseQueuedQueries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "queued_queries"),
+			"Number of queries currently queued on the warehouse.",
+			[]string{labelName, labelSize},
+			constLabels,
+		),
+		warehouseAutoSuspendSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "auto_suspend_seconds"),
+			"Number of seconds of inactivity after which the warehouse is suspended. Not reported if the warehouse is never suspended automatically.",
+			[]string{labelName, labelSize},
+			constLabels,
+		),
+		warehouseAutoResume: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "warehouse", "auto_resume"),
+			"Whether the warehouse is resumed automatically when a query is submitted to it. 1 indicates enabled, 0 disabled.",
+			[]string{labelName, labelSize},
+			constLabels,
+		),
+		resourceMonitorCreditQuota: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "resource_monitor", "credit_quota"),
+			"Number of credits the resource monitor allows to be used in its current interval.",
+			[]string{labelName},
+			constLabels,
+		),
+		resourceMonitorUsedCredits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "resource_monitor", "used_credits"),
+			"Number of credits used in the current interval of the resource monitor.",
+			[]string{labelName},
+			constLabels,
+		),
+		resourceMonitorRemainingCredits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "resource_monitor", "remaining_credits"),
+			"Number of credits remaining in the current interval of the resource monitor.",
+			[]string{labelName},
+			constLabels,
+		),
+		resourceMonitorThresholdPercent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "resource_monitor", "threshold_percent"),
+			"Percentage of the credit quota at which the resource monitor takes the action. A resource monitor may notify at several thresholds, each of which is its own series.",
+			[]string{labelName, labelAction, labelThreshold},
+			constLabels,
+		),
+		resourceMonitorWarehouse: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "resource_monitor", "warehouse"),
+			"Whether the warehouse is assigned to the resource monitor. Always 1.",
+			[]string{labelName, labelWarehouseName},
+			constLabels,
+		),
+		organizationUsageInCurrency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "organization", "usage_in_currency"),
+			"Spend of the account in the currency on the most recent day reported in ORGANIZATION_USAGE.",
+			[]string{labelAccountName, labelServiceType, labelUsageType, labelCurrency},
+			constLabels,
+		),
+		organizationEffectiveRate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "organization", "effective_rate"),
+			"Price in the currency of a unit of the usage type for the account on the most recent day reported in ORGANIZATION_USAGE.",
+			[]string{labelAccountName, labelServiceType, labelUsageType, labelCurrency},
+			constLabels,
+		),
+		organizationRemainingBalance: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "organization", "remaining_balance"),
+			"Remaining balance of the contract in the currency at the end of the most recent day reported in ORGANIZATION_USAGE.",
+			[]string{labelContractNumber, labelCurrency, labelBalanceType},
+			constLabels,
 		),
 		up: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "up"),
 			"Metric indicating the status of the exporter collection. 1 indicates that the connection Snowflake was successful, and all available metrics were collected. "+
 				"0 indicates that the exporter failed to collect 1 or more metrics, due to an inability to connect to Snowflake.",
 			nil,
-			nil,
+			constLabels,
+		),
+		cacheAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "cache_age_seconds"),
+			"Number of seconds since the served metrics of the collector were last refreshed from Snowflake.",
+			[]string{labelCollector},
+			constLabels,
+		),
+		collectorSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_success"),
+			"Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.",
+			[]string{labelCollector},
+			constLabels,
 		),
+		collectorDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_duration_seconds"),
+			"Number of seconds the most recent run of the collector took.",
+			[]string{labelCollector},
+			constLabels,
+		),
+		collectorRows: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_rows"),
+			"Number of rows returned by Snowflake during the most recent run of the collector.",
+			[]string{labelCollector},
+			constLabels,
+		),
+	}
+
+	col.scrapers = []scraper{
+		{
+			name:   collectorStorage,
+			descs:  []*prometheus.Desc{col.storageBytes, col.stageBytes, col.failsafeBytes},
+			scrape: col.collectStorageMetrics,
+		},
+		{
+			name:   collectorDatabaseStorage,
+			descs:  []*prometheus.Desc{col.databaseBytes, col.databaseFailsafeBytes},
+			scrape: col.collectDatabaseStorageMetrics,
+		},
+		{
+			name:   collectorCredits,
+			descs:  []*prometheus.Desc{col.usedComputeCredits, col.usedCloudServicesCredits},
+			scrape: col.collectCreditMetrics,
+		},
+		{
+			name:   collectorWarehouseCredits,
+			descs:  []*prometheus.Desc{col.warehouseUsedComputeCredits, col.warehouseUsedCloudServicesCredits},
+			scrape: col.collectWarehouseCreditMetrics,
+		},
+		{
+			name:   collectorLogins,
+			descs:  []*prometheus.Desc{col.logins, col.successfulLogins, col.failedLogins},
+			scrape: col.collectLoginMetrics,
+		},
+		{
+			name: collectorWarehouseLoad,
+			descs: []*prometheus.Desc{
+				col.warehouseExecutedQueryLoad, col.warehouseOverloadedQueueLoad,
+				col.warehouseProvisioningQueueLoad, col.warehouseBlockedQueryLoad,
+			},
+			scrape: col.collectWarehouseLoadMetrics,
+		},
+		{
+			name:   collectorAutoClustering,
+			descs:  []*prometheus.Desc{col.autoClusteringCredits, col.autoClusteringBytes, col.autoClusteringRows},
+			scrape: col.collectAutoClusteringMetrics,
+		},
+		{
+			name:   collectorTableStorage,
+			descs:  []*prometheus.Desc{col.tableActiveBytes, col.tableTimeTravelBytes, col.tableFailsafeBytes, col.tableCloneBytes},
+			scrape: col.collectTableStorageMetrics,
+		},
+		{
+			name:   collectorDeletedTables,
+			descs:  []*prometheus.Desc{col.tableDeletedTables},
+			scrape: col.collectDeletedTablesMetrics,
+		},
+		{
+			name:   collectorReplication,
+			descs:  []*prometheus.Desc{col.replicationUsedCredits, col.replicationTransferredBytes},
+			scrape: col.collectReplicationMetrics,
+		},
+		{
+			name:   collectorQueryLatency,
+			descs:  []*prometheus.Desc{col.queryElapsedSeconds, col.queryQueuedOverloadSeconds, col.queryCompilationLatencySeconds},
+			scrape: col.collectQueryLatencyMetrics,
+		},
+		{
+			name:   collectorQueryEfficiency,
+			descs:  []*prometheus.Desc{col.querySpilledLocalBytes, col.querySpilledRemoteBytes, col.queryScannedBytes, col.queryScannedPartitions, col.queryTotalPartitions},
+			scrape: col.collectQueryEfficiencyMetrics,
+		},
+		{
+			name:   collectorTasks,
+			descs:  []*prometheus.Desc{col.taskRuns, col.taskLastRunDurationSeconds, col.taskLastSuccessTimestamp},
+			scrape: col.collectTaskMetrics,
+		},
+		{
+			name:   collectorServerlessTasks,
+			descs:  []*prometheus.Desc{col.serverlessTaskCredits},
+			scrape: col.collectServerlessTaskMetrics,
+		},
+		{
+			name:   collectorPipeUsage,
+			descs:  []*prometheus.Desc{col.pipeCredits, col.pipeInsertedBytes, col.pipeInsertedFiles},
+			scrape: col.collectPipeUsageMetrics,
+		},
+		{
+			name:   collectorCopyHistory,
+			descs:  []*prometheus.Desc{col.copyErrors, col.copyLastLoadTimestamp},
+			scrape: col.collectCopyHistoryMetrics,
+		},
+		{
+			name:   collectorMaterializedViews,
+			descs:  []*prometheus.Desc{col.materializedViewCredits},
+			scrape: col.collectMaterializedViewMetrics,
+		},
+		{
+			name:   collectorSearchOptimization,
+			descs:  []*prometheus.Desc{col.searchOptimizationCredits},
+			scrape: col.collectSearchOptimizationMetrics,
+		},
+		{
+			name: collectorDynamicTables,
+			descs: []*prometheus.Desc{
+				col.dynamicTableRefreshes, col.dynamicTableLastRefreshSeconds, col.dynamicTableLagSeconds,
+				col.dynamicTableTargetLagSeconds, col.dynamicTableLastSuccessTimestamp,
+			},
+			scrape: col.collectDynamicTableMetrics,
+		},
+		{
+			name:   collectorDataTransfer,
+			descs:  []*prometheus.Desc{col.dataTransferBytes},
+			scrape: col.collectDataTransferMetrics,
+		},
+		{
+			name:   collectorWarehouseEvents,
+			descs:  []*prometheus.Desc{col.warehouseEvents, col.warehouseObservedClusters},
+			scrape: col.collectWarehouseEventMetrics,
+		},
+		{
+			name: collectorWarehouseState,
+			descs: []*prometheus.Desc{
+				col.warehouseState, col.warehouseMinClusters, col.warehouseMaxClusters, col.warehouseStartedClusters,
+				col.warehouseRunningQueries, col.warehouseQueuedQueries, col.warehouseAutoSuspendSeconds, col.warehouseAutoResume,
+			},
+			scrape: col.collectWarehouseStateMetrics,
+		},
+		{
+			name: collectorResourceMonitors,
+			descs: []*prometheus.Desc{
+				col.resourceMonitorCreditQuota, col.resourceMonitorUsedCredits, col.resourceMonitorRemainingCredits,
+				col.resourceMonitorThresholdPercent, col.resourceMonitorWarehouse,
+			},
+			scrape: col.collectResourceMonitorMetrics,
+		},
+		{
+			name:   collectorOrganizationUsage,
+			descs:  []*prometheus.Desc{col.organizationUsageInCurrency, col.organizationEffectiveRate},
+			scrape: col.collectOrganizationUsageMetrics,
+		},
+		{
+			name:   collectorOrganizationBalance,
+			descs:  []*prometheus.Desc{col.organizationRemainingBalance},
+			scrape: col.collectOrganizationBalanceMetrics,
+		},
+	}
+
+	col.settings.Store(col.newSettings(c))
+
+	return col
+}
+
+// settings holds the config of the collector along with the scrapers derived from it,
+// so that both are swapped together when the config is replaced.
+type settings struct {
+	config *Config
+	// connection is read when the config is set, so that the private key file is only read again on reload.
+	connection connectionSettings
+	// scrapers are the scrapers of all collectors, including those whose metrics depend on the config.
+	scrapers       []scraper
+	customScrapers []scraper
+}
+
+// newSettings creates the settings for the given config.
+func (c *Collector) newSettings(config *Config) *settings {
+	s := &settings{
+		config:     config,
+		connection: config.connectionSettings(),
+		scrapers:   append(slices.Clone(c.scrapers), c.newQueryHistoryScraper(config)),
+	}
+	for _, q := range config.CustomQueries {
+		s.customScrapers = append(s.customScrapers, c.newCustomScraper(q))
+	}
+	return s
+}
+
+// cfg returns the current config of the collector.
+func (c *Collector) cfg() *Config {
+	return c.settings.Load().config
+}
+
+// SetConfig replaces the config of the collector. The config is assumed to be valid.
+// Cached metrics are kept, and are refreshed according to the new config.
+func (c *Collector) SetConfig(config *Config) {
+	c.settings.Store(c.newSettings(config))
+
+	select {
+	case c.configChanged <- struct{}{}:
+	default:
+	}
+}
+
+// enabledScrapers returns the scrapers of the collectors enabled in the config, followed by
+// the scrapers of all custom queries.
+func (c *Collector) enabledScrapers() []scraper {
+	settings := c.settings.Load()
+
+	var enabled []scraper
+	for _, s := range settings.scrapers {
+		if settings.config.collectorEnabled(s.name) {
+			enabled = append(enabled, s)
+		}
 	}
+	return append(enabled, settings.customScrapers...)
+}
+
+// scraperInterval returns how often the scraper should be refreshed.
+func (c *Collector) scraperInterval(s scraper) time.Duration {
+	if s.interval > 0 {
+		return s.interval
+	}
+	return c.cfg().collectorInterval(s.name)
 }
 
 // Describe returns all metric descriptions of the collector by emitting them down the provided channel.
 // It implements prometheus.Collector.
 func (c *Collector) Describe(descs chan<- *prometheus.Desc) {
-	descs <- c.storageBytes
-	descs <- c.stageBytes
-	descs <- c.failsafeBytes
-	descs <- c.databaseBytes
-	descs <- c.databaseFailsafeBytes
-	descs <- c.usedComputeCredits
-	descs <- c.usedCloudServicesCredits
-	descs <- c.warehouseUsedComputeCredits
-	descs <- c.warehouseUsedCloudServicesCredits
-	descs <- c.logins
-	descs <- c.successfulLogins
-	descs <- c.failedLogins
-	descs <- c.warehouseExecutedQueryLoad
-	descs <- c.warehouseOverloadedQueueLoad
-	descs <- c.warehouseProvisioningQueueLoad
-	descs <- c.warehouseBlockedQueryLoad
-	descs <- c.autoClusteringCredits
-	descs <- c.autoClusteringBytes
-	descs <- c.autoClusteringRows
-	descs <- c.tableActiveBytes
-	descs <- c.tableTimeTravelBytes
-	descs <- c.tableFailsafeBytes
-	descs <- c.tableCloneBytes
-	descs <- c.tableDeletedTables
-	descs <- c.replicationUsedCredits
-	descs <- c.replicationTransferredBytes
+	for _, s := range c.enabledScrapers() {
+		for _, desc := range s.descs {
+			descs <- desc
+		}
+	}
 	descs <- c.up
+	descs <- c.cacheAge
+	descs <- c.collectorSuccess
+	descs <- c.collectorDuration
+	descs <- c.collectorRows
 }
 
-// Collect collects all metrics for this collector, and emits them through the provided channel.
+// Collect emits the most recently refreshed metrics of each enabled collector through the provided channel.
+// If no refresh interval is configured, collectors that are due are run before emitting the metrics.
 // It implements prometheus.Collector.
 func (c *Collector) Collect(metrics chan<- prometheus.Metric) {
-	c.logger.Debug("Collecting metrics.")
+	c.collectContext(context.Background(), metrics)
+}
 
-	// Create a WaitGroup to block closing the database until all goroutines are done
-	var wg sync.WaitGroup
+// contextCollector is a prometheus.Collector which bounds the queries run during a scrape with a context.
+type contextCollector struct {
+	*Collector
+	ctx context.Context
+}
 
-	var up atomic.Bool
-	up.Store(true)
-	// Open a new connection to the database each time; This makes the connection more robust to transient failures
-	connectionString, err := c.config.snowflakeConnectionString()
-	if err != nil {
-		c.logger.Error("Failed to generate connection string.", "err", err)
-		// Emit up metric here, to indicate connection failed.
-		metrics <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
-		return
-	}
-	db, err := c.openDatabase(connectionString)
-	if err != nil {
-		c.logger.Error("Failed to connect to Snowflake.", "err", err)
-		// Emit up metric here, to indicate connection failed.
-		metrics <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
-		return
+// Collect implements prometheus.Collector.
+func (c contextCollector) Collect(metrics chan<- prometheus.Metric) {
+	c.collectContext(c.ctx, metrics)
+}
+
+// WithContext returns a prometheus.Collector which collects the metrics of c,
+// cancelling any queries run during the scrape once ctx is done.
+func (c *Collector) WithContext(ctx context.Context) prometheus.Collector {
+	return contextCollector{Collector: c, ctx: ctx}
+}
+
+// collectContext implements Collect, running any due collectors with the given context.
+func (c *Collector) collectContext(ctx context.Context, metrics chan<- prometheus.Metric) {
+	if c.cfg().RefreshInterval <= 0 {
+		c.refresh(ctx)
 	}
-	defer func() { _ = db.Close() }()
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectStorageMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect storage metrics.", "err", err)
-			up.Store(false)
-		}
-		wg.Done()
-	}()
+	c.cacheMtx.RLock()
+	defer c.cacheMtx.RUnlock()
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectDatabaseStorageMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect database storage metrics.", "err", err)
-			up.Store(false)
+	up := true
+	for _, s := range c.enabledScrapers() {
+		result, ok := c.results[s.name]
+		if !ok {
+			// The collector has not been run yet
+			up = false
+			continue
 		}
-		wg.Done()
-	}()
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectCreditMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect credit metrics.", "err", err)
-			up.Store(false)
+		for _, m := range result.metrics {
+			metrics <- m
 		}
-		wg.Done()
-	}()
+		success := 0.0
+		if result.success {
+			success = 1
+		}
+		metrics <- prometheus.MustNewConstMetric(c.cacheAge, prometheus.GaugeValue, c.now().Sub(result.time).Seconds(), s.name)
+		metrics <- prometheus.MustNewConstMetric(c.collectorSuccess, prometheus.GaugeValue, success, s.name)
+		metrics <- prometheus.MustNewConstMetric(c.collectorDuration, prometheus.GaugeValue, result.duration.Seconds(), s.name)
+		metrics <- prometheus.MustNewConstMetric(c.collectorRows, prometheus.GaugeValue, float64(result.rows), s.name)
+		up = up && result.success
+	}
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectWarehouseCreditMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect warehouse credit metrics.", "err", err)
-			up.Store(false)
+	upValue := 0.0
+	if up {
+		upValue = 1
+	}
+	metrics <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, upValue)
+}
+
+// Run refreshes the cached metrics in the background until the context is cancelled.
+// While no refresh interval is configured, it waits for the config to be replaced.
+// The connection pool to Snowflake is closed when Run returns.
+func (c *Collector) Run(ctx context.Context) {
+	defer func() { _ = c.Close() }()
+
+	for {
+		var tick <-chan time.Time
+		if c.cfg().RefreshInterval > 0 {
+			c.refresh(ctx)
+			tick = time.After(c.refreshTick())
 		}
-		wg.Done()
-	}()
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectLoginMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect login metrics.", "err", err)
-			up.Store(false)
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.configChanged:
+		case <-tick:
 		}
-		wg.Done()
-	}()
+	}
+}
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectWarehouseLoadMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect warehouse load metrics.", "err", err)
-			up.Store(false)
+// refreshTick returns how often Run checks for collectors that are due to be refreshed.
+func (c *Collector) refreshTick() time.Duration {
+	tick := c.cfg().RefreshInterval
+	for _, s := range c.enabledScrapers() {
+		tick = min(tick, c.scraperInterval(s))
+	}
+	return tick
+}
+
+// refresh runs the enabled collectors whose cached results are older than their refresh interval,
+// and caches the results.
+func (c *Collector) refresh(ctx context.Context) {
+	c.refreshMtx.Lock()
+	defer c.refreshMtx.Unlock()
+
+	var due []scraper
+	c.cacheMtx.RLock()
+	for _, s := range c.enabledScrapers() {
+		result, ok := c.results[s.name]
+		if !ok || c.now().Sub(result.time) >= c.scraperInterval(s) {
+			due = append(due, s)
+			continue
 		}
-		wg.Done()
-	}()
+		// Failed collectors are retried on the next refresh instead of waiting for their interval.
+		if !result.success && c.now().Sub(result.attempt) >= c.cfg().RefreshInterval {
+			due = append(due, s)
+		}
+	}
+	c.cacheMtx.RUnlock()
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectAutoClusteringMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect autoclustering metrics.", "err", err)
-			up.Store(false)
+	if len(due) == 0 {
+		return
+	}
+
+	results := c.collect(ctx, due)
+
+	c.cacheMtx.Lock()
+	defer c.cacheMtx.Unlock()
+	for name, result := range results {
+		if previous, ok := c.results[name]; ok && !result.success {
+			// Keep serving the metrics of the previous run until the collector succeeds again
+			result.metrics = previous.metrics
+			result.time = previous.time
 		}
-		wg.Done()
-	}()
+		c.results[name] = result
+	}
+}
 
-	wg.Add(1)
-	go func() {
-		if err := c.collectTableStorageMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect table storage metrics.", "err", err)
-			up.Store(false)
+// collect runs the given scrapers against Snowflake and returns their results, keyed by collector name.
+func (c *Collector) collect(ctx context.Context, scrapers []scraper) map[string]scrapeResult {
+	c.logger.Debug("Collecting metrics.")
+
+	results := make(map[string]scrapeResult, len(scrapers))
+	failAll := func() map[string]scrapeResult {
+		for _, s := range scrapers {
+			results[s.name] = scrapeResult{time: c.now(), attempt: c.now()}
 		}
-		wg.Done()
-	}()
+		return results
+	}
+
+	db, err := c.database(ctx)
+	if err != nil {
+		c.logger.Error("Failed to connect to Snowflake.", "err", err)
+		return failAll()
+	}
 
-	if !c.config.ExcludeDeleted {
+	// Create a WaitGroup to wait for all scrapers to finish
+	var wg sync.WaitGroup
+	var resultsMtx sync.Mutex
+	for _, s := range scrapers {
 		wg.Add(1)
 		go func() {
-			if err := c.collectDeletedTablesMetrics(db, metrics); err != nil {
-				c.logger.Error("Failed to collect deleted tables metrics.", "err", err)
-				up.Store(false)
-			}
-			wg.Done()
+			defer wg.Done()
+			result := c.runScraper(ctx, db, s)
+
+			resultsMtx.Lock()
+			results[s.name] = result
+			resultsMtx.Unlock()
 		}()
 	}
 
-	wg.Add(1)
+	wg.Wait()
+	c.logger.Debug("Finished collecting metrics.")
+	return results
+}
+
+// database returns the connection pool to Snowflake, opening it if needed. An open pool is health
+// checked with a ping, and only re-opened if the ping fails or the connection settings of the config
+// changed, so that Snowflake is not logged in to on every refresh.
+func (c *Collector) database(ctx context.Context) (*sql.DB, error) {
+	c.dbMtx.Lock()
+	defer c.dbMtx.Unlock()
+
+	settings := c.settings.Load()
+	config := settings.config
+	// The pool is sized for all enabled collectors rather than those due in this refresh, so that
+	// refreshes of fewer collectors do not close the idle connections of the others.
+	concurrency := len(c.enabledScrapers())
+	if c.db != nil && c.dbSettings != settings.connection {
+		c.logger.Info("Connection settings changed, reconnecting to Snowflake.")
+		c.closeDatabase()
+	}
+
+	if c.db != nil {
+		err := c.db.PingContext(ctx)
+		if err == nil {
+			configurePool(c.db, config, concurrency)
+			return c.db, nil
+		}
+		if ctx.Err() != nil {
+			// The scrape timed out, which says nothing about the health of the connection.
+			return nil, err
+		}
+		c.logger.Warn("Connection to Snowflake failed health check, reconnecting.", "err", err)
+		c.closeDatabase()
+	}
+
+	connectionString, err := config.snowflakeConnectionString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate connection string: %w", err)
+	}
+	db, err := c.openDatabase(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	configurePool(db, config, concurrency)
+
+	c.db = db
+	c.dbSettings = settings.connection
+	return db, nil
+}
+
+// configurePool applies the connection pool limits of the config. Unless limited, as many idle
+// connections are kept as are used concurrently, so that connections are not logged in again
+// on the next refresh.
+func configurePool(db *sql.DB, config *Config, concurrency int) {
+	maxIdle := config.MaxIdleConns
+	if maxIdle == 0 {
+		maxIdle = config.MaxOpenConns
+	}
+	if maxIdle == 0 {
+		maxIdle = concurrency
+	}
+
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+}
+
+// closeDatabase closes the connection pool. dbMtx must be held.
+func (c *Collector) closeDatabase() {
+	if c.db == nil {
+		return
+	}
+	if err := c.db.Close(); err != nil {
+		c.logger.Warn("Failed to close connection to Snowflake.", "err", err)
+	}
+	c.db = nil
+}
+
+// Close closes the connection pool to Snowflake. The pool is opened again if the collector is
+// used after being closed.
+func (c *Collector) Close() error {
+	c.dbMtx.Lock()
+	defer c.dbMtx.Unlock()
+
+	if c.db == nil {
+		return nil
+	}
+	err := c.db.Close()
+	c.db = nil
+	return err
+}
+
+// runScraper runs a single scraper and buffers the metrics it emits.
+// The scraper's queries are cancelled if its timeout elapses.
+func (c *Collector) runScraper(ctx context.Context, db *sql.DB, s scraper) scrapeResult {
+	if timeout := c.cfg().collectorTimeout(s.name); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	metrics := make(chan prometheus.Metric)
+	collected := make(chan []prometheus.Metric)
 	go func() {
-		if err := c.collectReplicationMetrics(db, metrics); err != nil {
-			c.logger.Error("Failed to collect replication metrics.", "err", err)
-			up.Store(false)
+		var ms []prometheus.Metric
+		for m := range metrics {
+			ms = append(ms, m)
 		}
-		wg.Done()
+		collected <- ms
 	}()
 
-	wg.Wait()
-	upValue := 0.0
-	if up.Load() {
-		upValue = 1
+	start := c.now()
+	rows, err := s.scrape(ctx, db, metrics)
+	close(metrics)
+	end := c.now()
+	if err != nil {
+		c.logger.Error("Failed to collect metrics.", "collector", s.name, "err", err)
+	}
+
+	return scrapeResult{
+		metrics:  <-collected,
+		success:  err == nil,
+		duration: end.Sub(start),
+		rows:     rows,
+		time:     end,
+		attempt:  end,
 	}
-	metrics <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, upValue)
-	c.logger.Debug("Finished collecting metrics.")
 }
 
-func (c *Collector) collectStorageMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectStorageMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting storage metrics.")
-	rows, err := db.Query(storageMetricQuery)
+	rows, err := db.QueryContext(ctx, storageMetricQuery)
 	c.logger.Debug("Done querying storage metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
 	if !rows.Next() {
 		if err := rows.Err(); err != nil {
-			return fmt.Errorf("failed to fetch row: %w", rows.Err())
+			return 0, fmt.Errorf("failed to fetch row: %w", rows.Err())
 		}
-		return fmt.Errorf("expected a single row to be returned, but none was found")
+		return 0, fmt.Errorf("expected a single row to be returned, but none was found")
 	}
 
 	var storageBytes, stageBytes, failsafeBytes sql.NullFloat64
 	if err := rows.Scan(&storageBytes, &stageBytes, &failsafeBytes); err != nil {
-		return fmt.Errorf("failed to scan row: %w", err)
+		return 0, fmt.Errorf("failed to scan row: %w", err)
 	}
 
 	if storageBytes.Valid {
@@ -451,23 +1324,25 @@ func (c *Collector) collectStorageMetrics(db *sql.DB, metrics chan<- prometheus.
 	}
 
 	c.logger.Debug("Finished collecting storage metrics.")
-	return rows.Err()
+	return 1, rows.Err()
 }
 
-func (c *Collector) collectDatabaseStorageMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectDatabaseStorageMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting database storage metrics.")
-	rows, err := db.Query(databaseStorageMetricQuery)
+	rows, err := db.QueryContext(ctx, databaseStorageMetricQuery)
 	c.logger.Debug("Done querying database storage metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var dbName, dbID sql.NullString
 		var databaseBytes, failsafeBytes sql.NullFloat64
 		if err := rows.Scan(&dbName, &dbID, &databaseBytes, &failsafeBytes); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if databaseBytes.Valid {
@@ -479,23 +1354,25 @@ func (c *Collector) collectDatabaseStorageMetrics(db *sql.DB, metrics chan<- pro
 	}
 
 	c.logger.Debug("Finished collecting database storage metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectCreditMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectCreditMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting credit metrics.")
-	rows, err := db.Query(creditMetricQuery)
+	rows, err := db.QueryContext(ctx, creditMetricQuery)
 	c.logger.Debug("Done querying credit metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var serviceType, serviceName sql.NullString
 		var computeCreditsUsedAvg, cloudServiceCreditsUsedAvg sql.NullFloat64
 		if err := rows.Scan(&serviceType, &serviceName, &computeCreditsUsedAvg, &cloudServiceCreditsUsedAvg); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if computeCreditsUsedAvg.Valid {
@@ -507,23 +1384,25 @@ func (c *Collector) collectCreditMetrics(db *sql.DB, metrics chan<- prometheus.M
 	}
 
 	c.logger.Debug("Finished collecting credit metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectWarehouseCreditMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectWarehouseCreditMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting warehouse credit metrics.")
-	rows, err := db.Query(warehouseCreditMetricQuery)
+	rows, err := db.QueryContext(ctx, warehouseCreditMetricQuery)
 	c.logger.Debug("Done querying warehouse credit metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var warehouseName, warehouseID sql.NullString
 		var computeCreditsUsedAvg, cloudServiceCreditsUsedAvg sql.NullFloat64
 		if err := rows.Scan(&warehouseName, &warehouseID, &computeCreditsUsedAvg, &cloudServiceCreditsUsedAvg); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if computeCreditsUsedAvg.Valid {
@@ -535,23 +1414,25 @@ func (c *Collector) collectWarehouseCreditMetrics(db *sql.DB, metrics chan<- pro
 	}
 
 	c.logger.Debug("Finished collecting warehouse credit metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectLoginMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectLoginMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting login metrics.")
-	rows, err := db.Query(loginMetricQuery)
+	rows, err := db.QueryContext(ctx, loginMetricQuery)
 	c.logger.Debug("Done querying login metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var clientType, clientVersion sql.NullString
 		var failures, successes, total sql.NullFloat64
 		if err := rows.Scan(&clientType, &clientVersion, &failures, &successes, &total); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		// Divided by 24 to get the per-hour average
@@ -567,23 +1448,25 @@ func (c *Collector) collectLoginMetrics(db *sql.DB, metrics chan<- prometheus.Me
 	}
 
 	c.logger.Debug("Finished collecting login metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectWarehouseLoadMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectWarehouseLoadMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting warehouse load metrics.")
-	rows, err := db.Query(warehouseLoadMetricQuery)
+	rows, err := db.QueryContext(ctx, warehouseLoadMetricQuery)
 	c.logger.Debug("Done querying warehouse load metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var warehouseName, warehouseID sql.NullString
 		var avgRunning, avgQueued, avgQueuedProvisioning, avgBlocked sql.NullFloat64
 		if err := rows.Scan(&warehouseName, &warehouseID, &avgRunning, &avgQueued, &avgQueuedProvisioning, &avgBlocked); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if avgRunning.Valid {
@@ -601,24 +1484,26 @@ func (c *Collector) collectWarehouseLoadMetrics(db *sql.DB, metrics chan<- prome
 	}
 
 	c.logger.Debug("Finished collecting warehouse load metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectAutoClusteringMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectAutoClusteringMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting auto-clustering metrics.")
-	rows, err := db.Query(autoClusteringMetricQuery)
+	rows, err := db.QueryContext(ctx, autoClusteringMetricQuery)
 	c.logger.Debug("Done querying auto-clustering metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var tableName, tableID, databaseName, databaseID, schemaName, schemaID sql.NullString
 		var creditsUsed, bytesReclustered, rowsReclustered sql.NullFloat64
 		if err := rows.Scan(&tableName, &tableID, &schemaName, &schemaID, &databaseName, &databaseID,
 			&creditsUsed, &bytesReclustered, &rowsReclustered); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if creditsUsed.Valid {
@@ -636,34 +1521,36 @@ func (c *Collector) collectAutoClusteringMetrics(db *sql.DB, metrics chan<- prom
 	}
 
 	c.logger.Debug("Finished collecting auto-clustering metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectTableStorageMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectTableStorageMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	var rows *sql.Rows
 	var err error
-	if c.config.ExcludeDeleted {
+	if c.cfg().ExcludeDeleted {
 		c.logger.Debug("Collecting table storage metrics excluding deleted tables.")
-		rows, err = db.Query(tableStorageExcludeDeletedMetricQuery)
+		rows, err = db.QueryContext(ctx, tableStorageExcludeDeletedMetricQuery)
 		if err != nil {
-			return fmt.Errorf("failed to query metrics: %w", err)
+			return 0, fmt.Errorf("failed to query metrics: %w", err)
 		}
 	} else {
 		c.logger.Debug("Collecting table storage metrics.")
-		rows, err = db.Query(tableStorageMetricQuery)
+		rows, err = db.QueryContext(ctx, tableStorageMetricQuery)
 		if err != nil {
-			return fmt.Errorf("failed to query metrics: %w", err)
+			return 0, fmt.Errorf("failed to query metrics: %w", err)
 		}
 	}
 	c.logger.Debug("Done querying table storage metrics.")
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var tableName, tableID, databaseName, databaseID, schemaName, schemaID sql.NullString
 		var activeBytes, timeTravelBytes, failsafeBytes, cloneBytes sql.NullFloat64
 		if err := rows.Scan(&tableName, &tableID, &schemaName, &schemaID, &databaseName, &databaseID,
 			&activeBytes, &timeTravelBytes, &failsafeBytes, &cloneBytes); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if activeBytes.Valid {
@@ -685,22 +1572,24 @@ func (c *Collector) collectTableStorageMetrics(db *sql.DB, metrics chan<- promet
 	}
 
 	c.logger.Debug("Finished collecting table storage metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectDeletedTablesMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectDeletedTablesMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting deleted table metrics.")
-	rows, err := db.Query(deletedTablesMetricQuery)
+	rows, err := db.QueryContext(ctx, deletedTablesMetricQuery)
 	c.logger.Debug("Done querying deleted table metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var deletedTables sql.NullFloat64
 		if err := rows.Scan(&deletedTables); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if deletedTables.Valid {
@@ -709,23 +1598,25 @@ func (c *Collector) collectDeletedTablesMetrics(db *sql.DB, metrics chan<- prome
 	}
 
 	c.logger.Debug("Finished collecting deleted table metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
 }
 
-func (c *Collector) collectReplicationMetrics(db *sql.DB, metrics chan<- prometheus.Metric) error {
+func (c *Collector) collectReplicationMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
 	c.logger.Debug("Collecting replication metrics.")
-	rows, err := db.Query(replicationMetricQuery)
+	rows, err := db.QueryContext(ctx, replicationMetricQuery)
 	c.logger.Debug("Done querying replication metrics.")
 	if err != nil {
-		return fmt.Errorf("failed to query metrics: %w", err)
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
+	numRows := 0
 	for rows.Next() {
+		numRows++
 		var databaseName, databaseID sql.NullString
 		var creditsUsed, bytesTransferred sql.NullFloat64
 		if err := rows.Scan(&databaseName, &databaseID, &creditsUsed, &bytesTransferred); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		if creditsUsed.Valid {
@@ -737,5 +1628,872 @@ func (c *Collector) collectReplicationMetrics(db *sql.DB, metrics chan<- prometh
 	}
 
 	c.logger.Debug("Finished collecting replication metrics.")
-	return rows.Err()
+	return numRows, rows.Err()
+}
+
+// queryHistoryDescs holds the descriptors of the query_history collector, whose labels are the configured dimensions.
+type queryHistoryDescs struct {
+	dimensions []queryHistoryDimension
+	// errorDimensions are the dimensions of errors, which are split by error code instead of execution status.
+	errorDimensions    []queryHistoryDimension
+	queries            *prometheus.Desc
+	errors             *prometheus.Desc
+	executionSeconds   *prometheus.Desc
+	compilationSeconds *prometheus.Desc
+	queuedSeconds      *prometheus.Desc
+}
+
+// newQueryHistoryScraper creates the scraper of the query_history collector, split by the dimensions of the config.
+func (c *Collector) newQueryHistoryScraper(config *Config) scraper {
+	d := &queryHistoryDescs{dimensions: config.queryHistoryDimensions()}
+
+	var labels, errorLabels []string
+	for _, dimension := range d.dimensions {
+		labels = append(labels, dimension.label)
+		// The execution status of errors is always FAIL
+		if dimension.label != labelExecutionStatus {
+			d.errorDimensions = append(d.errorDimensions, dimension)
+			errorLabels = append(errorLabels, dimension.label)
+		}
+	}
+	errorLabels = append(errorLabels, labelErrorCode)
+
+	d.queries = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "queries"),
+		"Number of queries started over the last 24 hours.",
+		labels,
+		c.constLabels,
+	)
+	d.errors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "query", "errors"),
+		"Number of queries started over the last 24 hours which failed with the error code.",
+		errorLabels,
+		c.constLabels,
+	)
+	d.executionSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "query", "execution_seconds"),
+		"Sum of the execution time of queries started over the last 24 hours.",
+		labels,
+		c.constLabels,
+	)
+	d.compilationSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "query", "compilation_seconds"),
+		"Sum of the compilation time of queries started over the last 24 hours.",
+		labels,
+		c.constLabels,
+	)
+	d.queuedSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "query", "queued_seconds"),
+		"Sum of the time queries started over the last 24 hours spent queued, due to provisioning, repair, or overload of the warehouse.",
+		labels,
+		c.constLabels,
+	)
+
+	return scraper{
+		name:  collectorQueryHistory,
+		descs: []*prometheus.Desc{d.queries, d.errors, d.executionSeconds, d.compilationSeconds, d.queuedSeconds},
+		scrape: func(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+			return c.collectQueryHistoryMetrics(ctx, db, d, metrics)
+		},
+	}
+}
+
+func (c *Collector) collectQueryHistoryMetrics(ctx context.Context, db *sql.DB, d *queryHistoryDescs, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting query history metrics.")
+
+	// Only the configured dimensions are selected and grouped by.
+	var columns, errorColumns strings.Builder
+	for _, dimension := range d.dimensions {
+		columns.WriteString(dimension.column + ", ")
+	}
+	for _, dimension := range d.errorDimensions {
+		errorColumns.WriteString(dimension.column + ", ")
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(queryHistoryMetricQuery, columns.String()))
+	c.logger.Debug("Done querying query history metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		labels := make([]sql.NullString, len(d.dimensions))
+		var queries, executionTime, compilationTime, queuedTime sql.NullFloat64
+		dest := make([]any, 0, len(labels)+4)
+		for i := range labels {
+			dest = append(dest, &labels[i])
+		}
+		dest = append(dest, &queries, &executionTime, &compilationTime, &queuedTime)
+		if err := rows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		labelValues := nullStrings(labels)
+		if queries.Valid {
+			metrics <- prometheus.MustNewConstMetric(d.queries, prometheus.GaugeValue, queries.Float64, labelValues...)
+		}
+		if executionTime.Valid {
+			metrics <- prometheus.MustNewConstMetric(d.executionSeconds, prometheus.GaugeValue, executionTime.Float64/1000, labelValues...)
+		}
+		if compilationTime.Valid {
+			metrics <- prometheus.MustNewConstMetric(d.compilationSeconds, prometheus.GaugeValue, compilationTime.Float64/1000, labelValues...)
+		}
+		if queuedTime.Valid {
+			metrics <- prometheus.MustNewConstMetric(d.queuedSeconds, prometheus.GaugeValue, queuedTime.Float64/1000, labelValues...)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	errorRows, err := db.QueryContext(ctx, fmt.Sprintf(queryHistoryErrorMetricQuery, errorColumns.String()))
+	c.logger.Debug("Done querying query error metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query error metrics: %w", err)
+	}
+	defer func() { _ = errorRows.Close() }()
+
+	for errorRows.Next() {
+		numRows++
+		// The error code is the last label, following the dimensions
+		labels := make([]sql.NullString, len(d.errorDimensions)+1)
+		var queryErrors sql.NullFloat64
+		dest := make([]any, 0, len(labels)+1)
+		for i := range labels {
+			dest = append(dest, &labels[i])
+		}
+		dest = append(dest, &queryErrors)
+		if err := errorRows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if queryErrors.Valid {
+			metrics <- prometheus.MustNewConstMetric(d.errors, prometheus.GaugeValue, queryErrors.Float64, nullStrings(labels)...)
+		}
+	}
+
+	c.logger.Debug("Finished collecting query history metrics.")
+	return numRows, errorRows.Err()
+}
+
+func (c *Collector) collectQueryLatencyMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting query latency metrics.")
+
+	// Queries are bucketed by Snowflake, so that only one row per warehouse is returned. For each column,
+	// its sum is followed by the cumulative number of queries in each bucket.
+	buckets := c.cfg().queryLatencyBuckets()
+	var aggregates []string
+	for _, column := range queryLatencyColumns {
+		aggregates = append(aggregates, fmt.Sprintf("sum(%s)", column))
+		for _, bucket := range buckets {
+			aggregates = append(aggregates, fmt.Sprintf("count_if(%s <= %s)", column, strconv.FormatFloat(bucket*1000, 'f', -1, 64)))
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(queryLatencyMetricQuery, strings.Join(aggregates, ", ")))
+	c.logger.Debug("Done querying query latency metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	descs := []*prometheus.Desc{c.queryElapsedSeconds, c.queryQueuedOverloadSeconds, c.queryCompilationLatencySeconds}
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var warehouseName sql.NullString
+		var count sql.NullFloat64
+		values := make([]sql.NullFloat64, len(aggregates))
+		dest := make([]any, 0, len(values)+2)
+		dest = append(dest, &warehouseName, &count)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		for i, desc := range descs {
+			columnValues := values[i*(len(buckets)+1) : (i+1)*(len(buckets)+1)]
+			sum, bucketCounts := columnValues[0], columnValues[1:]
+
+			histogramBuckets := make(map[float64]uint64, len(buckets))
+			for j, bucket := range buckets {
+				histogramBuckets[bucket] = uint64(bucketCounts[j].Float64)
+			}
+			metrics <- prometheus.MustNewConstHistogram(desc, uint64(count.Float64), sum.Float64/1000, histogramBuckets, warehouseName.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting query latency metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectQueryEfficiencyMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting query efficiency metrics.")
+	rows, err := db.QueryContext(ctx, queryEfficiencyMetricQuery)
+	c.logger.Debug("Done querying query efficiency metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var warehouseName, warehouseSize sql.NullString
+		var spilledLocal, spilledRemote, scannedBytes, scannedPartitions, totalPartitions sql.NullFloat64
+		if err := rows.Scan(&warehouseName, &warehouseSize, &spilledLocal, &spilledRemote, &scannedBytes, &scannedPartitions, &totalPartitions); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if spilledLocal.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.querySpilledLocalBytes, prometheus.GaugeValue, spilledLocal.Float64, warehouseName.String, warehouseSize.String)
+		}
+		if spilledRemote.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.querySpilledRemoteBytes, prometheus.GaugeValue, spilledRemote.Float64, warehouseName.String, warehouseSize.String)
+		}
+		if scannedBytes.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.queryScannedBytes, prometheus.GaugeValue, scannedBytes.Float64, warehouseName.String, warehouseSize.String)
+		}
+		if scannedPartitions.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.queryScannedPartitions, prometheus.GaugeValue, scannedPartitions.Float64, warehouseName.String, warehouseSize.String)
+		}
+		if totalPartitions.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.queryTotalPartitions, prometheus.GaugeValue, totalPartitions.Float64, warehouseName.String, warehouseSize.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting query efficiency metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectTaskMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting task metrics.")
+	rows, err := db.QueryContext(ctx, taskRunMetricQuery)
+	c.logger.Debug("Done querying task run metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var taskName, schemaName, databaseName, state sql.NullString
+		var runs sql.NullFloat64
+		if err := rows.Scan(&taskName, &schemaName, &databaseName, &state, &runs); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if runs.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.taskRuns, prometheus.GaugeValue, runs.Float64, taskName.String, schemaName.String, databaseName.String, state.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	lastRows, err := db.QueryContext(ctx, taskLastRunMetricQuery)
+	c.logger.Debug("Done querying task last run metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = lastRows.Close() }()
+
+	for lastRows.Next() {
+		numRows++
+		var taskName, schemaName, databaseName sql.NullString
+		var lastRunDuration, lastSuccessTime sql.NullFloat64
+		if err := lastRows.Scan(&taskName, &schemaName, &databaseName, &lastRunDuration, &lastSuccessTime); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if lastRunDuration.Valid {
+			// Durations are returned in ms
+			metrics <- prometheus.MustNewConstMetric(c.taskLastRunDurationSeconds, prometheus.GaugeValue, lastRunDuration.Float64/1000, taskName.String, schemaName.String, databaseName.String)
+		}
+		if lastSuccessTime.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.taskLastSuccessTimestamp, prometheus.GaugeValue, lastSuccessTime.Float64, taskName.String, schemaName.String, databaseName.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting task metrics.")
+	return numRows, lastRows.Err()
+}
+
+func (c *Collector) collectServerlessTaskMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting serverless task metrics.")
+	rows, err := db.QueryContext(ctx, serverlessTaskMetricQuery)
+	c.logger.Debug("Done querying serverless task metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var taskName, schemaName, databaseName sql.NullString
+		var credits sql.NullFloat64
+		if err := rows.Scan(&taskName, &schemaName, &databaseName, &credits); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if credits.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.serverlessTaskCredits, prometheus.GaugeValue, credits.Float64, taskName.String, schemaName.String, databaseName.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting serverless task metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectPipeUsageMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting pipe usage metrics.")
+	rows, err := db.QueryContext(ctx, pipeUsageMetricQuery)
+	c.logger.Debug("Done querying pipe usage metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var pipeName, schemaName, databaseName sql.NullString
+		var credits, bytesInserted, filesInserted sql.NullFloat64
+		if err := rows.Scan(&pipeName, &schemaName, &databaseName, &credits, &bytesInserted, &filesInserted); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if credits.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.pipeCredits, prometheus.GaugeValue, credits.Float64, pipeName.String, schemaName.String, databaseName.String)
+		}
+		if bytesInserted.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.pipeInsertedBytes, prometheus.GaugeValue, bytesInserted.Float64, pipeName.String, schemaName.String, databaseName.String)
+		}
+		if filesInserted.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.pipeInsertedFiles, prometheus.GaugeValue, filesInserted.Float64, pipeName.String, schemaName.String, databaseName.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting pipe usage metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectCopyHistoryMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting copy history metrics.")
+	rows, err := db.QueryContext(ctx, copyHistoryMetricQuery)
+	c.logger.Debug("Done querying copy history metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var tableName, schemaName, databaseName sql.NullString
+		var errorCount, lastLoadTime sql.NullFloat64
+		if err := rows.Scan(&tableName, &schemaName, &databaseName, &errorCount, &lastLoadTime); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if errorCount.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.copyErrors, prometheus.GaugeValue, errorCount.Float64, tableName.String, schemaName.String, databaseName.String)
+		}
+		if lastLoadTime.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.copyLastLoadTimestamp, prometheus.GaugeValue, lastLoadTime.Float64, tableName.String, schemaName.String, databaseName.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting copy history metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectMaterializedViewMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting materialized view metrics.")
+	rows, err := db.QueryContext(ctx, materializedViewMetricQuery)
+	c.logger.Debug("Done querying materialized view metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var tableName, tableID, schemaName, schemaID, databaseName, databaseID sql.NullString
+		var credits sql.NullFloat64
+		if err := rows.Scan(&tableName, &tableID, &schemaName, &schemaID, &databaseName, &databaseID, &credits); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if credits.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.materializedViewCredits, prometheus.GaugeValue, credits.Float64,
+				tableName.String, tableID.String, schemaName.String, schemaID.String, databaseName.String, databaseID.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting materialized view metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectSearchOptimizationMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting search optimization metrics.")
+	rows, err := db.QueryContext(ctx, searchOptimizationMetricQuery)
+	c.logger.Debug("Done querying search optimization metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var tableName, tableID, schemaName, schemaID, databaseName, databaseID sql.NullString
+		var credits sql.NullFloat64
+		if err := rows.Scan(&tableName, &tableID, &schemaName, &schemaID, &databaseName, &databaseID, &credits); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if credits.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.searchOptimizationCredits, prometheus.GaugeValue, credits.Float64,
+				tableName.String, tableID.String, schemaName.String, schemaID.String, databaseName.String, databaseID.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting search optimization metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectDynamicTableMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting dynamic table metrics.")
+	rows, err := db.QueryContext(ctx, dynamicTableRefreshMetricQuery)
+	c.logger.Debug("Done querying dynamic table refresh metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var tableName, schemaName, databaseName, state sql.NullString
+		var refreshes sql.NullFloat64
+		if err := rows.Scan(&tableName, &schemaName, &databaseName, &state, &refreshes); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if refreshes.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.dynamicTableRefreshes, prometheus.GaugeValue, refreshes.Float64, tableName.String, schemaName.String, databaseName.String, state.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	lastRows, err := db.QueryContext(ctx, dynamicTableLastRefreshMetricQuery)
+	c.logger.Debug("Done querying dynamic table last refresh metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = lastRows.Close() }()
+
+	for lastRows.Next() {
+		numRows++
+		var tableName, schemaName, databaseName sql.NullString
+		var lastRefreshDuration, lag, targetLag, lastSuccessTime sql.NullFloat64
+		if err := lastRows.Scan(&tableName, &schemaName, &databaseName, &lastRefreshDuration, &lag, &targetLag, &lastSuccessTime); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if lastRefreshDuration.Valid {
+			// Durations are returned in ms
+			metrics <- prometheus.MustNewConstMetric(c.dynamicTableLastRefreshSeconds, prometheus.GaugeValue, lastRefreshDuration.Float64/1000, tableName.String, schemaName.String, databaseName.String)
+		}
+		if lag.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.dynamicTableLagSeconds, prometheus.GaugeValue, lag.Float64, tableName.String, schemaName.String, databaseName.String)
+		}
+		if targetLag.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.dynamicTableTargetLagSeconds, prometheus.GaugeValue, targetLag.Float64, tableName.String, schemaName.String, databaseName.String)
+		}
+		if lastSuccessTime.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.dynamicTableLastSuccessTimestamp, prometheus.GaugeValue, lastSuccessTime.Float64, tableName.String, schemaName.String, databaseName.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting dynamic table metrics.")
+	return numRows, lastRows.Err()
+}
+
+func (c *Collector) collectDataTransferMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting data transfer metrics.")
+	rows, err := db.QueryContext(ctx, dataTransferMetricQuery)
+	c.logger.Debug("Done querying data transfer metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var sourceCloud, sourceRegion, targetCloud, targetRegion, transferType sql.NullString
+		var bytesTransferred sql.NullFloat64
+		if err := rows.Scan(&sourceCloud, &sourceRegion, &targetCloud, &targetRegion, &transferType, &bytesTransferred); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if bytesTransferred.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.dataTransferBytes, prometheus.GaugeValue, bytesTransferred.Float64,
+				sourceCloud.String, sourceRegion.String, targetCloud.String, targetRegion.String, transferType.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting data transfer metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectWarehouseEventMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting warehouse event metrics.")
+	rows, err := db.QueryContext(ctx, warehouseEventMetricQuery)
+	c.logger.Debug("Done querying warehouse event metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var name, id, eventName sql.NullString
+		var events sql.NullFloat64
+		if err := rows.Scan(&name, &id, &eventName, &events); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if events.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.warehouseEvents, prometheus.GaugeValue, events.Float64, name.String, id.String, eventName.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	clusterRows, err := db.QueryContext(ctx, warehouseClusterMetricQuery)
+	c.logger.Debug("Done querying warehouse cluster metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = clusterRows.Close() }()
+
+	for clusterRows.Next() {
+		numRows++
+		var name, id sql.NullString
+		var clusters sql.NullFloat64
+		if err := clusterRows.Scan(&name, &id, &clusters); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if clusters.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.warehouseObservedClusters, prometheus.GaugeValue, clusters.Float64, name.String, id.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting warehouse event metrics.")
+	return numRows, clusterRows.Err()
+}
+
+func (c *Collector) collectWarehouseStateMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting warehouse state metrics.")
+	rows, err := db.QueryContext(ctx, warehouseStateMetricQuery)
+	c.logger.Debug("Done querying warehouse state metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	// The columns returned by SHOW commands vary between Snowflake releases, so they are looked up by name.
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns: %w", err)
+	}
+	names := []string{"name", "state", "size", "min_cluster_count", "max_cluster_count", "started_clusters", "running", "queued", "auto_suspend", "auto_resume"}
+	indexes, err := columnIndexes(columns, names)
+	if err != nil {
+		return 0, err
+	}
+	gauges := []*prometheus.Desc{
+		c.warehouseMinClusters, c.warehouseMaxClusters, c.warehouseStartedClusters,
+		c.warehouseRunningQueries, c.warehouseQueuedQueries, c.warehouseAutoSuspendSeconds,
+	}
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		name, state, size := values[indexes[0]].String, values[indexes[1]].String, values[indexes[2]].String
+
+		for _, s := range warehouseStates {
+			metrics <- prometheus.MustNewConstMetric(c.warehouseState, prometheus.GaugeValue, boolToFloat(s == state), name, size, s)
+		}
+		if state != "" && !slices.Contains(warehouseStates, state) {
+			metrics <- prometheus.MustNewConstMetric(c.warehouseState, prometheus.GaugeValue, 1, name, size, state)
+		}
+
+		for j, desc := range gauges {
+			value := values[indexes[j+3]]
+			// auto_suspend is null if the warehouse is never suspended automatically
+			if !value.Valid || value.String == "" || strings.EqualFold(value.String, "null") {
+				continue
+			}
+			f, err := strconv.ParseFloat(value.String, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse value of column %s: %w", names[j+3], err)
+			}
+			metrics <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, f, name, size)
+		}
+
+		if autoResume := values[indexes[9]]; autoResume.Valid {
+			b, err := strconv.ParseBool(autoResume.String)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse value of column auto_resume: %w", err)
+			}
+			metrics <- prometheus.MustNewConstMetric(c.warehouseAutoResume, prometheus.GaugeValue, boolToFloat(b), name, size)
+		}
+	}
+
+	c.logger.Debug("Finished collecting warehouse state metrics.")
+	return numRows, rows.Err()
+}
+
+func (c *Collector) collectResourceMonitorMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting resource monitor metrics.")
+	rows, err := db.QueryContext(ctx, resourceMonitorMetricQuery)
+	c.logger.Debug("Done querying resource monitor metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns: %w", err)
+	}
+	names := []string{"name", "credit_quota", "used_credits", "remaining_credits", "notify_at", "suspend_at", "suspend_immediately_at"}
+	indexes, err := columnIndexes(columns, names)
+	if err != nil {
+		return 0, err
+	}
+	gauges := []*prometheus.Desc{c.resourceMonitorCreditQuota, c.resourceMonitorUsedCredits, c.resourceMonitorRemainingCredits}
+	actions := []string{"notify", "suspend", "suspend_immediate"}
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		name := values[indexes[0]].String
+
+		for j, desc := range gauges {
+			value := values[indexes[j+1]]
+			// The credit quota is null if the resource monitor has none
+			if !value.Valid || value.String == "" || strings.EqualFold(value.String, "null") {
+				continue
+			}
+			f, err := strconv.ParseFloat(value.String, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse value of column %s: %w", names[j+1], err)
+			}
+			metrics <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, f, name)
+		}
+
+		for j, action := range actions {
+			percents, err := parsePercents(values[indexes[j+4]].String)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse value of column %s: %w", names[j+4], err)
+			}
+			// Repeated thresholds would be duplicate series
+			slices.Sort(percents)
+			for _, percent := range slices.Compact(percents) {
+				metrics <- prometheus.MustNewConstMetric(c.resourceMonitorThresholdPercent, prometheus.GaugeValue, percent, name, action, strconv.FormatFloat(percent, 'f', -1, 64))
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Resource monitors do not list their warehouses, so they are found from the warehouses' resource monitor.
+	warehouseRows, err := db.QueryContext(ctx, warehouseStateMetricQuery)
+	c.logger.Debug("Done querying resource monitor warehouses.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = warehouseRows.Close() }()
+
+	columns, err = warehouseRows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns: %w", err)
+	}
+	indexes, err = columnIndexes(columns, []string{"name", "resource_monitor"})
+	if err != nil {
+		return 0, err
+	}
+
+	for warehouseRows.Next() {
+		numRows++
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := warehouseRows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		monitor := values[indexes[1]]
+		if monitor.Valid && monitor.String != "" && !strings.EqualFold(monitor.String, "null") {
+			metrics <- prometheus.MustNewConstMetric(c.resourceMonitorWarehouse, prometheus.GaugeValue, 1, monitor.String, values[indexes[0]].String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting resource monitor metrics.")
+	return numRows, warehouseRows.Err()
+}
+
+func (c *Collector) collectOrganizationUsageMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting organization usage metrics.")
+	usageRows, err := db.QueryContext(ctx, organizationUsageMetricQuery)
+	c.logger.Debug("Done querying organization usage metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = usageRows.Close() }()
+
+	numRows := 0
+	for usageRows.Next() {
+		numRows++
+		var accountName, serviceType, usageType, currency sql.NullString
+		var usage sql.NullFloat64
+		if err := usageRows.Scan(&accountName, &serviceType, &usageType, &currency, &usage); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if usage.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.organizationUsageInCurrency, prometheus.GaugeValue, usage.Float64, accountName.String, serviceType.String, usageType.String, currency.String)
+		}
+	}
+	if err := usageRows.Err(); err != nil {
+		return 0, err
+	}
+
+	rateRows, err := db.QueryContext(ctx, organizationRateMetricQuery)
+	c.logger.Debug("Done querying organization rate metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rateRows.Close() }()
+
+	for rateRows.Next() {
+		numRows++
+		var accountName, serviceType, usageType, currency sql.NullString
+		var rate sql.NullFloat64
+		if err := rateRows.Scan(&accountName, &serviceType, &usageType, &currency, &rate); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if rate.Valid {
+			metrics <- prometheus.MustNewConstMetric(c.organizationEffectiveRate, prometheus.GaugeValue, rate.Float64, accountName.String, serviceType.String, usageType.String, currency.String)
+		}
+	}
+
+	c.logger.Debug("Finished collecting organization usage metrics.")
+	return numRows, rateRows.Err()
+}
+
+func (c *Collector) collectOrganizationBalanceMetrics(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting organization balance metrics.")
+	rows, err := db.QueryContext(ctx, organizationBalanceMetricQuery)
+	c.logger.Debug("Done querying organization balance metrics.")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		var contractNumber, currency sql.NullString
+		balances := make([]sql.NullFloat64, len(balanceTypes))
+		dest := []any{&contractNumber, &currency}
+		for i := range balances {
+			dest = append(dest, &balances[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		for i, balance := range balances {
+			if balance.Valid {
+				metrics <- prometheus.MustNewConstMetric(c.organizationRemainingBalance, prometheus.GaugeValue, balance.Float64, contractNumber.String, currency.String, balanceTypes[i])
+			}
+		}
+	}
+
+	c.logger.Debug("Finished collecting organization balance metrics.")
+	return numRows, rows.Err()
+}
+
+// parsePercents parses a comma-separated list of percentages, such as "50%,75%", as returned by SHOW RESOURCE MONITORS.
+func parsePercents(s string) ([]float64, error) {
+	var percents []float64
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(p), "%"))
+		if p == "" || strings.EqualFold(p, "null") {
+			continue
+		}
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return nil, err
+		}
+		percents = append(percents, f)
+	}
+	return percents, nil
+}
+
+// boolToFloat returns 1 if b is true, and 0 otherwise.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// nullStrings returns the strings of the given values, which are empty if NULL.
+func nullStrings(values []sql.NullString) []string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = v.String
+	}
+	return strs
 }
@@ -15,12 +15,20 @@
 package collector
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,8 +45,14 @@ var ExampleConfig = &Config{
 	Password:    "defaultpassword",
 	Warehouse:   "defaultwarehouse",
 	Role:        "ACCOUNTADMIN",
+	// sqlmock mocks a single connection, which must not be closed before the pool is closed
+	MaxOpenConns: 1,
 }
 
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func fixedNow() time.Time { return testTime }
+
 func TestCollector_Collect(t *testing.T) {
 	t.Run("Metrics match expected", func(t *testing.T) {
 		db, mock := createMockDB(t)
@@ -46,6 +60,7 @@ func TestCollector_Collect(t *testing.T) {
 
 		col := NewCollector(promslog.NewNopLogger(), ExampleConfig)
 		col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+		col.now = fixedNow
 
 		f, err := os.Open(filepath.Join("testdata", "all_metrics.prom"))
 		require.NoError(t, err)
@@ -53,6 +68,7 @@ func TestCollector_Collect(t *testing.T) {
 
 		require.NoError(t, testutil.CollectAndCompare(col, f))
 
+		require.NoError(t, col.Close())
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -62,11 +78,13 @@ func TestCollector_Collect(t *testing.T) {
 
 		col := NewCollector(promslog.NewNopLogger(), ExampleConfig)
 		col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+		col.now = fixedNow
 
 		p, err := testutil.CollectAndLint(col)
 		require.NoError(t, err)
 		require.Empty(t, p)
 
+		require.NoError(t, col.Close())
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -76,6 +94,7 @@ func TestCollector_Collect(t *testing.T) {
 
 		col := NewCollector(promslog.NewNopLogger(), ExampleConfig)
 		col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+		col.now = fixedNow
 
 		f, err := os.Open(filepath.Join("testdata", "query_failure.prom"))
 		require.NoError(t, err)
@@ -87,6 +106,7 @@ func TestCollector_Collect(t *testing.T) {
 		require.NoError(t, err)
 
 		// Checks that db is closed and all queries are called
+		require.NoError(t, col.Close())
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -95,6 +115,7 @@ func TestCollector_Collect(t *testing.T) {
 
 		openErr := errors.New("failed to open database")
 		col.openDatabase = func(_ string) (*sql.DB, error) { return nil, openErr }
+		col.now = fixedNow
 
 		f, err := os.Open(filepath.Join("testdata", "query_failure.prom"))
 		require.NoError(t, err)
@@ -106,6 +127,517 @@ func TestCollector_Collect(t *testing.T) {
 	})
 }
 
+func TestCollector_Describe(t *testing.T) {
+	describe := func(col *Collector) []*prometheus.Desc {
+		descChan := make(chan *prometheus.Desc)
+		go func() {
+			col.Describe(descChan)
+			close(descChan)
+		}()
+
+		var descs []*prometheus.Desc
+		for desc := range descChan {
+			descs = append(descs, desc)
+		}
+		return descs
+	}
+
+	t.Run("All collectors enabled", func(t *testing.T) {
+		col := NewCollector(promslog.NewNopLogger(), ExampleConfig)
+		require.Len(t, describe(col), 31)
+	})
+
+	t.Run("Disabled collectors are not described", func(t *testing.T) {
+		config := *ExampleConfig
+		config.Collectors = map[string]bool{collectorTableStorage: false}
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		descs := describe(col)
+		require.Len(t, descs, 27)
+		require.NotContains(t, descs, col.tableActiveBytes)
+	})
+}
+
+func TestCollector_Collect_disabledCollectors(t *testing.T) {
+	db, mock := createStorageMockDB(t)
+
+	config := *ExampleConfig
+	config.Collectors = onlyCollectors(collectorStorage)
+
+	col := NewCollector(promslog.NewNopLogger(), &config)
+	col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+	col.now = fixedNow
+
+	// Storage metrics, collector self-metrics, and up
+	require.Equal(t, 8, testutil.CollectAndCount(col))
+	require.NoError(t, col.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollector_Collect_collectorIntervals(t *testing.T) {
+	config := *ExampleConfig
+	config.Collectors = onlyCollectors(collectorStorage, collectorDeletedTables)
+	config.CollectorIntervals = map[string]time.Duration{collectorStorage: time.Hour}
+
+	col := NewCollector(promslog.NewNopLogger(), &config)
+	col.now = fixedNow
+
+	expectDeletedTablesQuery := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(deletedTablesMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"NUM_TABLES"}).AddRow(10)).
+			RowsWillBeClosed()
+	}
+
+	// The first scrape runs all collectors
+	db, mock := createStorageMockDB(t)
+	expectDeletedTablesQuery(mock)
+	mock.MatchExpectationsInOrder(false)
+	col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+
+	require.Equal(t, 13, testutil.CollectAndCount(col))
+	require.NoError(t, col.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// Later scrapes only rerun collectors whose interval has elapsed
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	expectDeletedTablesQuery(mock)
+	mock.ExpectClose()
+	col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+	col.now = func() time.Time { return testTime.Add(time.Minute) }
+
+	expected := `
+# HELP snowflake_exporter_cache_age_seconds Number of seconds since the served metrics of the collector were last refreshed from Snowflake.
+# TYPE snowflake_exporter_cache_age_seconds gauge
+snowflake_exporter_cache_age_seconds{collector="deleted_tables"} 0
+snowflake_exporter_cache_age_seconds{collector="storage"} 60
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_exporter_cache_age_seconds"))
+	require.NoError(t, col.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollector_refresh_failures(t *testing.T) {
+	config := *ExampleConfig
+	config.RefreshInterval = time.Minute
+	config.CollectorIntervals = map[string]time.Duration{collectorStorage: 6 * time.Hour}
+
+	col, mock := newMockCollector(t, config, collectorStorage)
+	expectStorageQuery := func(storageBytes int) {
+		mock.ExpectQuery(storageMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"STORAGE_BYTES", "STAGE_BYTES", "FAILSAFE_BYTES"}).AddRow(storageBytes, 2, 3)).
+			RowsWillBeClosed()
+	}
+	refreshAt := func(d time.Duration) {
+		col.now = func() time.Time { return testTime.Add(d) }
+		col.refresh(context.Background())
+	}
+	names := []string{"snowflake_storage_bytes", "snowflake_exporter_collector_success", "snowflake_exporter_cache_age_seconds"}
+
+	expectStorageQuery(1)
+	refreshAt(0)
+
+	// A failed run keeps serving the metrics of the previous run
+	mock.ExpectQuery(storageMetricQuery).WillReturnError(errors.New("connection reset"))
+	refreshAt(6 * time.Hour)
+
+	expected := `
+# HELP snowflake_exporter_cache_age_seconds Number of seconds since the served metrics of the collector were last refreshed from Snowflake.
+# TYPE snowflake_exporter_cache_age_seconds gauge
+snowflake_exporter_cache_age_seconds{collector="storage"} 21600
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="storage"} 0
+# HELP snowflake_storage_bytes Number of bytes of table storage used, including bytes for data currently in Time Travel.
+# TYPE snowflake_storage_bytes gauge
+snowflake_storage_bytes 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), names...))
+
+	// The failed collector is not retried before the refresh interval elapses
+	refreshAt(6*time.Hour + 30*time.Second)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// It is retried once the refresh interval elapses, rather than its own interval
+	expectStorageQuery(4)
+	refreshAt(6*time.Hour + time.Minute)
+
+	expected = `
+# HELP snowflake_exporter_cache_age_seconds Number of seconds since the served metrics of the collector were last refreshed from Snowflake.
+# TYPE snowflake_exporter_cache_age_seconds gauge
+snowflake_exporter_cache_age_seconds{collector="storage"} 0
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="storage"} 1
+# HELP snowflake_storage_bytes Number of bytes of table storage used, including bytes for data currently in Time Travel.
+# TYPE snowflake_storage_bytes gauge
+snowflake_storage_bytes 4
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), names...))
+}
+
+func TestCollector_Collect_timeouts(t *testing.T) {
+	config := *ExampleConfig
+	config.Collectors = onlyCollectors(collectorStorage, collectorDeletedTables)
+	config.CollectorTimeouts = map[string]time.Duration{collectorDeletedTables: 10 * time.Millisecond}
+
+	db, mock := createStorageMockDB(t)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(deletedTablesMetricQuery).
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"NUM_TABLES"}).AddRow(10))
+
+	col := NewCollector(promslog.NewNopLogger(), &config)
+	col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+	col.now = fixedNow
+
+	expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="deleted_tables"} 0
+snowflake_exporter_collector_success{collector="storage"} 1
+# HELP snowflake_storage_bytes Number of bytes of table storage used, including bytes for data currently in Time Travel.
+# TYPE snowflake_storage_bytes gauge
+snowflake_storage_bytes 1
+# HELP snowflake_up Metric indicating the status of the exporter collection. 1 indicates that the connection Snowflake was successful, and all available metrics were collected. 0 indicates that the exporter failed to collect 1 or more metrics, due to an inability to connect to Snowflake.
+# TYPE snowflake_up gauge
+snowflake_up 0
+`
+
+	// The timed out collector fails, while the metrics of the other collectors are still returned
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_exporter_collector_success", "snowflake_storage_bytes", "snowflake_up"))
+	require.NoError(t, col.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollector_WithContext(t *testing.T) {
+	config := *ExampleConfig
+	config.Collectors = onlyCollectors(collectorDeletedTables)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	mock.ExpectQuery(deletedTablesMetricQuery).
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows([]string{"NUM_TABLES"}).AddRow(10))
+	mock.ExpectClose()
+
+	col := NewCollector(promslog.NewNopLogger(), &config)
+	col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+	col.now = fixedNow
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="deleted_tables"} 0
+`
+
+	// Queries are cancelled once the scrape's context is done
+	require.NoError(t, testutil.CollectAndCompare(col.WithContext(ctx), strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	require.NoError(t, col.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollector_Run(t *testing.T) {
+	t.Run("Metrics are served from cache", func(t *testing.T) {
+		db, mock := createStorageMockDB(t)
+
+		config := *ExampleConfig
+		config.RefreshInterval = time.Hour
+		config.Collectors = onlyCollectors(collectorStorage)
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+		col.now = fixedNow
+
+		// Nothing is served before the first refresh
+		require.Equal(t, 1, testutil.CollectAndCount(col))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runDone := make(chan struct{})
+		go func() {
+			col.Run(ctx)
+			close(runDone)
+		}()
+
+		require.Eventually(t, func() bool {
+			return testutil.CollectAndCount(col) > 1
+		}, time.Second, 10*time.Millisecond)
+		cancel()
+		<-runDone
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		col.now = func() time.Time { return testTime.Add(90 * time.Second) }
+
+		expected := `
+# HELP snowflake_exporter_cache_age_seconds Number of seconds since the served metrics of the collector were last refreshed from Snowflake.
+# TYPE snowflake_exporter_cache_age_seconds gauge
+snowflake_exporter_cache_age_seconds{collector="storage"} 90
+# HELP snowflake_up Metric indicating the status of the exporter collection. 1 indicates that the connection Snowflake was successful, and all available metrics were collected. 0 indicates that the exporter failed to collect 1 or more metrics, due to an inability to connect to Snowflake.
+# TYPE snowflake_up gauge
+snowflake_up 1
+`
+
+		// Repeated scrapes are served from the cache without querying Snowflake
+		for i := 0; i < 2; i++ {
+			require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_up", "snowflake_exporter_cache_age_seconds"))
+		}
+	})
+}
+
+func TestCollector_SetConfig(t *testing.T) {
+	t.Run("Collectors follow the new config", func(t *testing.T) {
+		config := *ExampleConfig
+		config.Collectors = onlyCollectors(collectorStorage)
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		col.now = fixedNow
+
+		newConfig := *ExampleConfig
+		newConfig.Collectors = onlyCollectors(collectorDeletedTables)
+		col.SetConfig(&newConfig)
+
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		mock.ExpectQuery(deletedTablesMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"NUM_TABLES"}).AddRow(10)).
+			RowsWillBeClosed()
+		mock.ExpectClose()
+		col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="deleted_tables"} 1
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_exporter_collector_success"))
+		require.NoError(t, col.Close())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Run starts refreshing once an interval is configured", func(t *testing.T) {
+		db, mock := createStorageMockDB(t)
+
+		config := *ExampleConfig
+		config.Collectors = onlyCollectors(collectorStorage)
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+		col.now = fixedNow
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runDone := make(chan struct{})
+		go func() {
+			col.Run(ctx)
+			close(runDone)
+		}()
+
+		newConfig := config
+		newConfig.RefreshInterval = time.Hour
+		col.SetConfig(&newConfig)
+
+		require.Eventually(t, func() bool {
+			return testutil.CollectAndCount(col) > 1
+		}, time.Second, 10*time.Millisecond)
+		cancel()
+		<-runDone
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCollector_database(t *testing.T) {
+	config := *ExampleConfig
+	config.Collectors = onlyCollectors(collectorDeletedTables)
+
+	expectDeletedTables := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(deletedTablesMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"NUM_TABLES"}).AddRow(10)).
+			RowsWillBeClosed()
+	}
+
+	t.Run("Connection is reused across refreshes", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		expectDeletedTables(mock)
+		expectDeletedTables(mock)
+		mock.ExpectClose()
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		opened := 0
+		col.openDatabase = func(_ string) (*sql.DB, error) {
+			opened++
+			return db, nil
+		}
+
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+		require.Equal(t, 1, opened)
+
+		require.NoError(t, col.Close())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Connection is reopened after failed health check", func(t *testing.T) {
+		unhealthy, unhealthyMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual), sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		expectDeletedTables(unhealthyMock)
+		unhealthyMock.ExpectPing().WillReturnError(driver.ErrBadConn)
+		unhealthyMock.ExpectClose()
+
+		healthy, healthyMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		expectDeletedTables(healthyMock)
+		healthyMock.ExpectClose()
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		dbs := []*sql.DB{unhealthy, healthy}
+		col.openDatabase = func(_ string) (*sql.DB, error) {
+			db := dbs[0]
+			dbs = dbs[1:]
+			return db, nil
+		}
+
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+
+		require.NoError(t, col.Close())
+		require.NoError(t, unhealthyMock.ExpectationsWereMet())
+		require.NoError(t, healthyMock.ExpectationsWereMet())
+	})
+
+	t.Run("Connection is reopened when credentials change", func(t *testing.T) {
+		oldDB, oldMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		expectDeletedTables(oldMock)
+		expectDeletedTables(oldMock)
+		oldMock.ExpectClose()
+
+		newDB, newMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		expectDeletedTables(newMock)
+		newMock.ExpectClose()
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		dbs := []*sql.DB{oldDB, newDB}
+		var connectionStrings []string
+		col.openDatabase = func(connStr string) (*sql.DB, error) {
+			connectionStrings = append(connectionStrings, connStr)
+			db := dbs[0]
+			dbs = dbs[1:]
+			return db, nil
+		}
+
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+
+		// Changing only the pool limits keeps the connection
+		newConfig := config
+		newConfig.MaxIdleConns = 4
+		col.SetConfig(&newConfig)
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+		require.Len(t, connectionStrings, 1)
+
+		newConfig.Password = "newpassword"
+		col.SetConfig(&newConfig)
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+		require.Len(t, connectionStrings, 2)
+		require.Contains(t, connectionStrings[1], "newpassword")
+
+		require.NoError(t, col.Close())
+		require.NoError(t, oldMock.ExpectationsWereMet())
+		require.NoError(t, newMock.ExpectationsWereMet())
+	})
+
+	t.Run("Connection is reopened when the private key is rotated", func(t *testing.T) {
+		writeKey := func(path string) {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			require.NoError(t, err)
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+		}
+
+		oldDB, oldMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		expectDeletedTables(oldMock)
+		oldMock.ExpectClose()
+
+		newDB, newMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		expectDeletedTables(newMock)
+		newMock.ExpectClose()
+
+		keyConfig := config
+		keyConfig.Password = ""
+		keyConfig.PrivateKeyPath = filepath.Join(t.TempDir(), "rsa_key.p8")
+		writeKey(keyConfig.PrivateKeyPath)
+
+		col := NewCollector(promslog.NewNopLogger(), &keyConfig)
+		dbs := []*sql.DB{oldDB, newDB}
+		var connectionStrings []string
+		col.openDatabase = func(connStr string) (*sql.DB, error) {
+			connectionStrings = append(connectionStrings, connStr)
+			db := dbs[0]
+			dbs = dbs[1:]
+			return db, nil
+		}
+
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+
+		// The key is replaced at the same path, and the unchanged config is reloaded
+		writeKey(keyConfig.PrivateKeyPath)
+		reloaded := keyConfig
+		col.SetConfig(&reloaded)
+		require.Equal(t, 6, testutil.CollectAndCount(col))
+		require.Len(t, connectionStrings, 2)
+		require.NotEqual(t, connectionStrings[0], connectionStrings[1])
+
+		require.NoError(t, col.Close())
+		require.NoError(t, oldMock.ExpectationsWereMet())
+		require.NoError(t, newMock.ExpectationsWereMet())
+	})
+}
+
+func TestNewAccountCollector(t *testing.T) {
+	healthyConfig := *ExampleConfig
+	healthyConfig.AccountName = "healthy"
+	healthyConfig.Collectors = onlyCollectors(collectorStorage)
+	healthy := NewAccountCollector(promslog.NewNopLogger(), &healthyConfig)
+	healthy.now = fixedNow
+	db, mock := createStorageMockDB(t)
+	healthy.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+
+	failingConfig := *ExampleConfig
+	failingConfig.AccountName = "failing"
+	failingConfig.Collectors = onlyCollectors(collectorStorage)
+	failing := NewAccountCollector(promslog.NewNopLogger(), &failingConfig)
+	failing.now = fixedNow
+	failing.openDatabase = func(_ string) (*sql.DB, error) { return nil, errors.New("failed to open database") }
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(healthy, failing)
+
+	expected := `
+# HELP snowflake_storage_bytes Number of bytes of table storage used, including bytes for data currently in Time Travel.
+# TYPE snowflake_storage_bytes gauge
+snowflake_storage_bytes{account="healthy"} 1
+# HELP snowflake_up Metric indicating the status of the exporter collection. 1 indicates that the connection Snowflake was successful, and all available metrics were collected. 0 indicates that the exporter failed to collect 1 or more metrics, due to an inability to connect to Snowflake.
+# TYPE snowflake_up gauge
+snowflake_up{account="failing"} 0
+snowflake_up{account="healthy"} 1
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{account="failing",collector="storage"} 0
+snowflake_exporter_collector_success{account="healthy",collector="storage"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"snowflake_storage_bytes", "snowflake_up", "snowflake_exporter_collector_success"))
+	require.NoError(t, healthy.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestCollector_collectStorageMetrics(t *testing.T) {
 	t.Run("Row error", func(t *testing.T) {
 		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
@@ -128,7 +660,7 @@ func TestCollector_collectStorageMetrics(t *testing.T) {
 		collectionDoneChan := make(chan struct{})
 
 		go func() {
-			err := col.collectStorageMetrics(db, metricChan)
+			_, err := col.collectStorageMetrics(context.Background(), db, metricChan)
 			require.ErrorContains(t, err, "failed to fetch row:")
 			require.ErrorIs(t, err, rowErr)
 			close(collectionDoneChan)
@@ -158,7 +690,7 @@ func TestCollector_collectStorageMetrics(t *testing.T) {
 		collectionDoneChan := make(chan struct{})
 
 		go func() {
-			err := col.collectStorageMetrics(db, metricChan)
+			_, err := col.collectStorageMetrics(context.Background(), db, metricChan)
 			require.Equal(t, "expected a single row to be returned, but none was found", err.Error())
 			close(collectionDoneChan)
 		}()
@@ -381,6 +913,53 @@ func createMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 	return db, mock
 }
 
+// createStorageMockDB creates a mock database which expects only the storage metrics query.
+func createStorageMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	mock.ExpectQuery(storageMetricQuery).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"STORAGE_BYTES", "STAGE_BYTES", "FAILSAFE_BYTES"}).AddRow(1, 2, 3),
+		).
+		RowsWillBeClosed()
+	mock.ExpectClose()
+
+	return db, mock
+}
+
+// newMockCollector returns a collector which only runs the named collector, against a mock database.
+// The mock's expectations are checked when the test ends.
+func newMockCollector(t *testing.T, config Config, name string) (*Collector, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	config.Collectors = onlyCollectors(name)
+	col := NewCollector(promslog.NewNopLogger(), &config)
+	col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+	col.now = fixedNow
+
+	t.Cleanup(func() {
+		mock.ExpectClose()
+		require.NoError(t, col.Close())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	return col, mock
+}
+
+// onlyCollectors returns a collector configuration which enables only the given collectors.
+func onlyCollectors(names ...string) map[string]bool {
+	collectors := map[string]bool{}
+	for name := range DefaultCollectors() {
+		collectors[name] = slices.Contains(names, name)
+	}
+	return collectors
+}
+
 func createQueryErrMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 	t.Helper()
 
@@ -404,3 +983,597 @@ func createQueryErrMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 
 	return db, mock
 }
+
+func TestCollector_collectQueryHistoryMetrics(t *testing.T) {
+	t.Run("All labels", func(t *testing.T) {
+		col, mock := newMockCollector(t, *ExampleConfig, collectorQueryHistory)
+		mock.ExpectQuery(fmt.Sprintf(queryHistoryMetricQuery, "WAREHOUSE_NAME, USER_NAME, ROLE_NAME, QUERY_TYPE, EXECUTION_STATUS, ")).
+			WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "USER_NAME", "ROLE_NAME", "QUERY_TYPE", "EXECUTION_STATUS", "COUNT", "EXECUTION_TIME", "COMPILATION_TIME", "QUEUED_TIME"}).
+				AddRow("COMPUTE_WH", "ALICE", "ANALYST", "SELECT", "SUCCESS", 10, 1500, 200, 0).
+				AddRow(nil, "SYSTEM", "ACCOUNTADMIN", "USE", "FAIL", 1, nil, 3, nil)).
+			RowsWillBeClosed()
+		mock.ExpectQuery(fmt.Sprintf(queryHistoryErrorMetricQuery, "WAREHOUSE_NAME, USER_NAME, ROLE_NAME, QUERY_TYPE, ")).
+			WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "USER_NAME", "ROLE_NAME", "QUERY_TYPE", "ERROR_CODE", "COUNT"}).
+				AddRow(nil, "SYSTEM", "ACCOUNTADMIN", "USE", "002043", 1)).
+			RowsWillBeClosed()
+
+		expected := `
+# HELP snowflake_queries Number of queries started over the last 24 hours.
+# TYPE snowflake_queries gauge
+snowflake_queries{execution_status="FAIL",query_type="USE",role_name="ACCOUNTADMIN",user_name="SYSTEM",warehouse_name=""} 1
+snowflake_queries{execution_status="SUCCESS",query_type="SELECT",role_name="ANALYST",user_name="ALICE",warehouse_name="COMPUTE_WH"} 10
+# HELP snowflake_query_compilation_seconds Sum of the compilation time of queries started over the last 24 hours.
+# TYPE snowflake_query_compilation_seconds gauge
+snowflake_query_compilation_seconds{execution_status="FAIL",query_type="USE",role_name="ACCOUNTADMIN",user_name="SYSTEM",warehouse_name=""} 0.003
+snowflake_query_compilation_seconds{execution_status="SUCCESS",query_type="SELECT",role_name="ANALYST",user_name="ALICE",warehouse_name="COMPUTE_WH"} 0.2
+# HELP snowflake_query_errors Number of queries started over the last 24 hours which failed with the error code.
+# TYPE snowflake_query_errors gauge
+snowflake_query_errors{error_code="002043",query_type="USE",role_name="ACCOUNTADMIN",user_name="SYSTEM",warehouse_name=""} 1
+# HELP snowflake_query_execution_seconds Sum of the execution time of queries started over the last 24 hours.
+# TYPE snowflake_query_execution_seconds gauge
+snowflake_query_execution_seconds{execution_status="SUCCESS",query_type="SELECT",role_name="ANALYST",user_name="ALICE",warehouse_name="COMPUTE_WH"} 1.5
+# HELP snowflake_query_queued_seconds Sum of the time queries started over the last 24 hours spent queued, due to provisioning, repair, or overload of the warehouse.
+# TYPE snowflake_query_queued_seconds gauge
+snowflake_query_queued_seconds{execution_status="SUCCESS",query_type="SELECT",role_name="ANALYST",user_name="ALICE",warehouse_name="COMPUTE_WH"} 0
+# HELP snowflake_exporter_collector_rows Number of rows returned by Snowflake during the most recent run of the collector.
+# TYPE snowflake_exporter_collector_rows gauge
+snowflake_exporter_collector_rows{collector="query_history"} 3
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+			"snowflake_queries", "snowflake_query_compilation_seconds", "snowflake_query_errors", "snowflake_query_execution_seconds",
+			"snowflake_query_queued_seconds", "snowflake_exporter_collector_rows"))
+	})
+
+	t.Run("Configured labels", func(t *testing.T) {
+		config := *ExampleConfig
+		config.QueryHistoryLabels = []string{labelExecutionStatus, labelWarehouseName}
+
+		col, mock := newMockCollector(t, config, collectorQueryHistory)
+		mock.ExpectQuery(fmt.Sprintf(queryHistoryMetricQuery, "WAREHOUSE_NAME, EXECUTION_STATUS, ")).
+			WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "EXECUTION_STATUS", "COUNT", "EXECUTION_TIME", "COMPILATION_TIME", "QUEUED_TIME"}).
+				AddRow("COMPUTE_WH", "SUCCESS", 10, 1500, 200, 0)).
+			RowsWillBeClosed()
+		mock.ExpectQuery(fmt.Sprintf(queryHistoryErrorMetricQuery, "WAREHOUSE_NAME, ")).
+			WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "ERROR_CODE", "COUNT"}).
+				AddRow("COMPUTE_WH", "000630", 4)).
+			RowsWillBeClosed()
+
+		expected := `
+# HELP snowflake_queries Number of queries started over the last 24 hours.
+# TYPE snowflake_queries gauge
+snowflake_queries{execution_status="SUCCESS",warehouse_name="COMPUTE_WH"} 10
+# HELP snowflake_query_errors Number of queries started over the last 24 hours which failed with the error code.
+# TYPE snowflake_query_errors gauge
+snowflake_query_errors{error_code="000630",warehouse_name="COMPUTE_WH"} 4
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_queries", "snowflake_query_errors"))
+	})
+
+	t.Run("Error query fails", func(t *testing.T) {
+		config := *ExampleConfig
+		config.QueryHistoryLabels = []string{labelWarehouseName}
+
+		col, mock := newMockCollector(t, config, collectorQueryHistory)
+		mock.ExpectQuery(fmt.Sprintf(queryHistoryMetricQuery, "WAREHOUSE_NAME, ")).
+			WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "COUNT", "EXECUTION_TIME", "COMPILATION_TIME", "QUEUED_TIME"})).
+			RowsWillBeClosed()
+		mock.ExpectQuery(fmt.Sprintf(queryHistoryErrorMetricQuery, "WAREHOUSE_NAME, ")).
+			WillReturnError(errors.New("insufficient privileges"))
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="query_history"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+}
+
+func TestCollector_collectQueryLatencyMetrics(t *testing.T) {
+	t.Run("Configured buckets", func(t *testing.T) {
+		config := *ExampleConfig
+		config.QueryLatencyBuckets = []float64{0.5, 10}
+
+		col, mock := newMockCollector(t, config, collectorQueryLatency)
+		mock.ExpectQuery(fmt.Sprintf(queryLatencyMetricQuery,
+			"sum(TOTAL_ELAPSED_TIME), count_if(TOTAL_ELAPSED_TIME <= 500), count_if(TOTAL_ELAPSED_TIME <= 10000), "+
+				"sum(QUEUED_OVERLOAD_TIME), count_if(QUEUED_OVERLOAD_TIME <= 500), count_if(QUEUED_OVERLOAD_TIME <= 10000), "+
+				"sum(COMPILATION_TIME), count_if(COMPILATION_TIME <= 500), count_if(COMPILATION_TIME <= 10000)")).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"WAREHOUSE_NAME", "COUNT",
+				"ELAPSED_SUM", "ELAPSED_500", "ELAPSED_10000",
+				"QUEUED_SUM", "QUEUED_500", "QUEUED_10000",
+				"COMPILATION_SUM", "COMPILATION_500", "COMPILATION_10000",
+			}).
+				AddRow("COMPUTE_WH", 10, 42000, 4, 9, 0, 10, 10, 1500, 8, 10)).
+			RowsWillBeClosed()
+
+		expected := `
+# HELP snowflake_query_latency_compilation_seconds Distribution of the compilation time of queries started on the warehouse over the last 24 hours.
+# TYPE snowflake_query_latency_compilation_seconds histogram
+snowflake_query_latency_compilation_seconds_bucket{warehouse_name="COMPUTE_WH",le="0.5"} 8
+snowflake_query_latency_compilation_seconds_bucket{warehouse_name="COMPUTE_WH",le="10"} 10
+snowflake_query_latency_compilation_seconds_bucket{warehouse_name="COMPUTE_WH",le="+Inf"} 10
+snowflake_query_latency_compilation_seconds_sum{warehouse_name="COMPUTE_WH"} 1.5
+snowflake_query_latency_compilation_seconds_count{warehouse_name="COMPUTE_WH"} 10
+# HELP snowflake_query_latency_queued_overload_seconds Distribution of the time queries started on the warehouse over the last 24 hours spent queued because the warehouse was overloaded.
+# TYPE snowflake_query_latency_queued_overload_seconds histogram
+snowflake_query_latency_queued_overload_seconds_bucket{warehouse_name="COMPUTE_WH",le="0.5"} 10
+snowflake_query_latency_queued_overload_seconds_bucket{warehouse_name="COMPUTE_WH",le="10"} 10
+snowflake_query_latency_queued_overload_seconds_bucket{warehouse_name="COMPUTE_WH",le="+Inf"} 10
+snowflake_query_latency_queued_overload_seconds_sum{warehouse_name="COMPUTE_WH"} 0
+snowflake_query_latency_queued_overload_seconds_count{warehouse_name="COMPUTE_WH"} 10
+# HELP snowflake_query_latency_total_elapsed_seconds Distribution of the elapsed time of queries started on the warehouse over the last 24 hours.
+# TYPE snowflake_query_latency_total_elapsed_seconds histogram
+snowflake_query_latency_total_elapsed_seconds_bucket{warehouse_name="COMPUTE_WH",le="0.5"} 4
+snowflake_query_latency_total_elapsed_seconds_bucket{warehouse_name="COMPUTE_WH",le="10"} 9
+snowflake_query_latency_total_elapsed_seconds_bucket{warehouse_name="COMPUTE_WH",le="+Inf"} 10
+snowflake_query_latency_total_elapsed_seconds_sum{warehouse_name="COMPUTE_WH"} 42
+snowflake_query_latency_total_elapsed_seconds_count{warehouse_name="COMPUTE_WH"} 10
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+			"snowflake_query_latency_compilation_seconds", "snowflake_query_latency_queued_overload_seconds", "snowflake_query_latency_total_elapsed_seconds"))
+	})
+
+	t.Run("Query fails", func(t *testing.T) {
+		config := *ExampleConfig
+		config.QueryLatencyBuckets = []float64{1}
+
+		col, mock := newMockCollector(t, config, collectorQueryLatency)
+		mock.ExpectQuery(fmt.Sprintf(queryLatencyMetricQuery,
+			"sum(TOTAL_ELAPSED_TIME), count_if(TOTAL_ELAPSED_TIME <= 1000), "+
+				"sum(QUEUED_OVERLOAD_TIME), count_if(QUEUED_OVERLOAD_TIME <= 1000), "+
+				"sum(COMPILATION_TIME), count_if(COMPILATION_TIME <= 1000)")).
+			WillReturnError(errors.New("insufficient privileges"))
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="query_latency"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+}
+
+func TestCollector_collectQueryEfficiencyMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorQueryEfficiency)
+	mock.ExpectQuery(queryEfficiencyMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "WAREHOUSE_SIZE", "LOCAL", "REMOTE", "BYTES_SCANNED", "PARTITIONS_SCANNED", "PARTITIONS_TOTAL"}).
+			AddRow("COMPUTE_WH", "X-Small", 1024, 0, 4096, 12, 48).
+			AddRow("COMPUTE_WH", "Large", nil, nil, 2048, 3, 3)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_query_scanned_bytes Number of bytes scanned by queries started on the warehouse over the last 24 hours.
+# TYPE snowflake_query_scanned_bytes gauge
+snowflake_query_scanned_bytes{size="Large",warehouse_name="COMPUTE_WH"} 2048
+snowflake_query_scanned_bytes{size="X-Small",warehouse_name="COMPUTE_WH"} 4096
+# HELP snowflake_query_scanned_partitions Number of micro-partitions scanned by queries started on the warehouse over the last 24 hours.
+# TYPE snowflake_query_scanned_partitions gauge
+snowflake_query_scanned_partitions{size="Large",warehouse_name="COMPUTE_WH"} 3
+snowflake_query_scanned_partitions{size="X-Small",warehouse_name="COMPUTE_WH"} 12
+# HELP snowflake_query_spilled_local_bytes Number of bytes spilled to local storage by queries started on the warehouse over the last 24 hours.
+# TYPE snowflake_query_spilled_local_bytes gauge
+snowflake_query_spilled_local_bytes{size="X-Small",warehouse_name="COMPUTE_WH"} 1024
+# HELP snowflake_query_spilled_remote_bytes Number of bytes spilled to remote storage by queries started on the warehouse over the last 24 hours.
+# TYPE snowflake_query_spilled_remote_bytes gauge
+snowflake_query_spilled_remote_bytes{size="X-Small",warehouse_name="COMPUTE_WH"} 0
+# HELP snowflake_query_total_partitions Number of micro-partitions of the tables read by queries started on the warehouse over the last 24 hours.
+# TYPE snowflake_query_total_partitions gauge
+snowflake_query_total_partitions{size="Large",warehouse_name="COMPUTE_WH"} 3
+snowflake_query_total_partitions{size="X-Small",warehouse_name="COMPUTE_WH"} 48
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_query_scanned_bytes", "snowflake_query_scanned_partitions", "snowflake_query_spilled_local_bytes",
+		"snowflake_query_spilled_remote_bytes", "snowflake_query_total_partitions"))
+}
+
+func TestCollector_collectTaskMetrics(t *testing.T) {
+	t.Run("Runs and last run", func(t *testing.T) {
+		col, mock := newMockCollector(t, *ExampleConfig, collectorTasks)
+		mock.ExpectQuery(taskRunMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"NAME", "SCHEMA_NAME", "DATABASE_NAME", "STATE", "COUNT"}).
+				AddRow("LOAD_ORDERS", "PUBLIC", "ANALYTICS", "SUCCEEDED", 23).
+				AddRow("LOAD_ORDERS", "PUBLIC", "ANALYTICS", "FAILED", 1).
+				AddRow("REFRESH_MARTS", "MARTS", "ANALYTICS", "SKIPPED", 24)).
+			RowsWillBeClosed()
+		mock.ExpectQuery(taskLastRunMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"NAME", "SCHEMA_NAME", "DATABASE_NAME", "LAST_RUN_DURATION", "LAST_SUCCESS_TIME"}).
+				AddRow("LOAD_ORDERS", "PUBLIC", "ANALYTICS", 42500, 1700000000).
+				AddRow("REFRESH_MARTS", "MARTS", "ANALYTICS", 1200, nil)).
+			RowsWillBeClosed()
+
+		expected := `
+# HELP snowflake_task_last_run_duration_seconds Duration of the most recent run of the task completed over the last 7 days.
+# TYPE snowflake_task_last_run_duration_seconds gauge
+snowflake_task_last_run_duration_seconds{database_name="ANALYTICS",schema_name="MARTS",task_name="REFRESH_MARTS"} 1.2
+snowflake_task_last_run_duration_seconds{database_name="ANALYTICS",schema_name="PUBLIC",task_name="LOAD_ORDERS"} 42.5
+# HELP snowflake_task_last_success_timestamp_seconds Unix timestamp of the completion of the most recent successful run of the task over the last 7 days.
+# TYPE snowflake_task_last_success_timestamp_seconds gauge
+snowflake_task_last_success_timestamp_seconds{database_name="ANALYTICS",schema_name="PUBLIC",task_name="LOAD_ORDERS"} 1.7e+09
+# HELP snowflake_task_runs Number of runs of the task scheduled over the last 24 hours which ended in the state.
+# TYPE snowflake_task_runs gauge
+snowflake_task_runs{database_name="ANALYTICS",schema_name="MARTS",state="SKIPPED",task_name="REFRESH_MARTS"} 24
+snowflake_task_runs{database_name="ANALYTICS",schema_name="PUBLIC",state="FAILED",task_name="LOAD_ORDERS"} 1
+snowflake_task_runs{database_name="ANALYTICS",schema_name="PUBLIC",state="SUCCEEDED",task_name="LOAD_ORDERS"} 23
+# HELP snowflake_exporter_collector_rows Number of rows returned by Snowflake during the most recent run of the collector.
+# TYPE snowflake_exporter_collector_rows gauge
+snowflake_exporter_collector_rows{collector="tasks"} 5
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+			"snowflake_task_last_run_duration_seconds", "snowflake_task_last_success_timestamp_seconds", "snowflake_task_runs",
+			"snowflake_exporter_collector_rows"))
+	})
+
+	t.Run("Last run query fails", func(t *testing.T) {
+		col, mock := newMockCollector(t, *ExampleConfig, collectorTasks)
+		mock.ExpectQuery(taskRunMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"NAME", "SCHEMA_NAME", "DATABASE_NAME", "STATE", "COUNT"})).
+			RowsWillBeClosed()
+		mock.ExpectQuery(taskLastRunMetricQuery).WillReturnError(errors.New("insufficient privileges"))
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="tasks"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+}
+
+func TestCollector_collectServerlessTaskMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorServerlessTasks)
+	mock.ExpectQuery(serverlessTaskMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"TASK_NAME", "SCHEMA_NAME", "DATABASE_NAME", "CREDITS_USED"}).
+			AddRow("LOAD_ORDERS", "PUBLIC", "ANALYTICS", 0.75).
+			AddRow("REFRESH_MARTS", "MARTS", "ANALYTICS", nil)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_serverless_task_credits Sum of the number of credits billed for serverless runs of the task over the last 24 hours.
+# TYPE snowflake_serverless_task_credits gauge
+snowflake_serverless_task_credits{database_name="ANALYTICS",schema_name="PUBLIC",task_name="LOAD_ORDERS"} 0.75
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_serverless_task_credits"))
+}
+
+func TestCollector_collectPipeUsageMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorPipeUsage)
+	mock.ExpectQuery(pipeUsageMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"PIPE_NAME", "PIPE_SCHEMA", "PIPE_CATALOG", "CREDITS_USED", "BYTES_INSERTED", "FILES_INSERTED"}).
+			AddRow("ORDERS_PIPE", "RAW", "INGEST", 0.12, 1048576, 8).
+			AddRow("DROPPED_PIPE", nil, nil, 0.01, nil, nil)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_pipe_credits Sum of the number of credits billed for loading data with the pipe over the last 24 hours.
+# TYPE snowflake_pipe_credits gauge
+snowflake_pipe_credits{database_name="",pipe_name="DROPPED_PIPE",schema_name=""} 0.01
+snowflake_pipe_credits{database_name="INGEST",pipe_name="ORDERS_PIPE",schema_name="RAW"} 0.12
+# HELP snowflake_pipe_inserted_bytes Number of bytes loaded by the pipe over the last 24 hours.
+# TYPE snowflake_pipe_inserted_bytes gauge
+snowflake_pipe_inserted_bytes{database_name="INGEST",pipe_name="ORDERS_PIPE",schema_name="RAW"} 1.048576e+06
+# HELP snowflake_pipe_inserted_files Number of files loaded by the pipe over the last 24 hours.
+# TYPE snowflake_pipe_inserted_files gauge
+snowflake_pipe_inserted_files{database_name="INGEST",pipe_name="ORDERS_PIPE",schema_name="RAW"} 8
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_pipe_credits", "snowflake_pipe_inserted_bytes", "snowflake_pipe_inserted_files"))
+}
+
+func TestCollector_collectCopyHistoryMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorCopyHistory)
+	mock.ExpectQuery(copyHistoryMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "TABLE_SCHEMA_NAME", "TABLE_CATALOG_NAME", "ERROR_COUNT", "LAST_LOAD_TIME"}).
+			AddRow("ORDERS", "RAW", "INGEST", 3, 1700000000).
+			AddRow("CUSTOMERS", "RAW", "INGEST", 0, 1699990000)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_copy_errors Number of errors encountered loading files into the table over the last 24 hours.
+# TYPE snowflake_copy_errors gauge
+snowflake_copy_errors{database_name="INGEST",schema_name="RAW",table_name="CUSTOMERS"} 0
+snowflake_copy_errors{database_name="INGEST",schema_name="RAW",table_name="ORDERS"} 3
+# HELP snowflake_copy_last_load_timestamp_seconds Unix timestamp of the most recent load of a file into the table over the last 7 days.
+# TYPE snowflake_copy_last_load_timestamp_seconds gauge
+snowflake_copy_last_load_timestamp_seconds{database_name="INGEST",schema_name="RAW",table_name="CUSTOMERS"} 1.69999e+09
+snowflake_copy_last_load_timestamp_seconds{database_name="INGEST",schema_name="RAW",table_name="ORDERS"} 1.7e+09
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_copy_errors", "snowflake_copy_last_load_timestamp_seconds"))
+}
+
+func TestCollector_collectMaterializedViewMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorMaterializedViews)
+	mock.ExpectQuery(materializedViewMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "TABLE_ID", "SCHEMA_NAME", "SCHEMA_ID", "DATABASE_NAME", "DATABASE_ID", "CREDITS_USED"}).
+			AddRow("DAILY_ORDERS", "12", "MARTS", "3", "ANALYTICS", "1", 1.25)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_materialized_view_credits Sum of the number of credits billed for refreshing the materialized view over the last 24 hours.
+# TYPE snowflake_materialized_view_credits gauge
+snowflake_materialized_view_credits{database_id="1",database_name="ANALYTICS",schema_id="3",schema_name="MARTS",table_id="12",table_name="DAILY_ORDERS"} 1.25
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_materialized_view_credits"))
+}
+
+func TestCollector_collectSearchOptimizationMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorSearchOptimization)
+	mock.ExpectQuery(searchOptimizationMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "TABLE_ID", "SCHEMA_NAME", "SCHEMA_ID", "DATABASE_NAME", "DATABASE_ID", "CREDITS_USED"}).
+			AddRow("EVENTS", "42", "RAW", "3", "ANALYTICS", "1", 4.5).
+			AddRow("USERS", "43", "RAW", "3", "ANALYTICS", "1", nil)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_search_optimization_credits Sum of the number of credits billed for maintaining the search access paths of the table over the last 24 hours.
+# TYPE snowflake_search_optimization_credits gauge
+snowflake_search_optimization_credits{database_id="1",database_name="ANALYTICS",schema_id="3",schema_name="RAW",table_id="42",table_name="EVENTS"} 4.5
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_search_optimization_credits"))
+}
+
+func TestCollector_collectDynamicTableMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorDynamicTables)
+	mock.ExpectQuery(dynamicTableRefreshMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"NAME", "SCHEMA_NAME", "DATABASE_NAME", "STATE", "COUNT"}).
+			AddRow("ORDERS_DT", "MARTS", "ANALYTICS", "SUCCEEDED", 287).
+			AddRow("ORDERS_DT", "MARTS", "ANALYTICS", "UPSTREAM_FAILED", 1)).
+		RowsWillBeClosed()
+	mock.ExpectQuery(dynamicTableLastRefreshMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"NAME", "SCHEMA_NAME", "DATABASE_NAME", "LAST_REFRESH_DURATION", "LAG", "TARGET_LAG_SEC", "LAST_SUCCESS_TIME"}).
+			AddRow("ORDERS_DT", "MARTS", "ANALYTICS", 3500, 420, 300, 1700000000).
+			AddRow("NEW_DT", "MARTS", "ANALYTICS", nil, nil, 60, nil)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_dynamic_table_lag_seconds Number of seconds between the data timestamp of the most recent successful refresh of the dynamic table and the current time.
+# TYPE snowflake_dynamic_table_lag_seconds gauge
+snowflake_dynamic_table_lag_seconds{database_name="ANALYTICS",schema_name="MARTS",table_name="ORDERS_DT"} 420
+# HELP snowflake_dynamic_table_last_refresh_duration_seconds Duration of the most recent refresh of the dynamic table completed over the last 7 days.
+# TYPE snowflake_dynamic_table_last_refresh_duration_seconds gauge
+snowflake_dynamic_table_last_refresh_duration_seconds{database_name="ANALYTICS",schema_name="MARTS",table_name="ORDERS_DT"} 3.5
+# HELP snowflake_dynamic_table_last_success_timestamp_seconds Unix timestamp of the completion of the most recent successful refresh of the dynamic table over the last 7 days.
+# TYPE snowflake_dynamic_table_last_success_timestamp_seconds gauge
+snowflake_dynamic_table_last_success_timestamp_seconds{database_name="ANALYTICS",schema_name="MARTS",table_name="ORDERS_DT"} 1.7e+09
+# HELP snowflake_dynamic_table_refreshes Number of refreshes of the dynamic table over the last 24 hours which ended in the state.
+# TYPE snowflake_dynamic_table_refreshes gauge
+snowflake_dynamic_table_refreshes{database_name="ANALYTICS",schema_name="MARTS",state="SUCCEEDED",table_name="ORDERS_DT"} 287
+snowflake_dynamic_table_refreshes{database_name="ANALYTICS",schema_name="MARTS",state="UPSTREAM_FAILED",table_name="ORDERS_DT"} 1
+# HELP snowflake_dynamic_table_target_lag_seconds Target lag of the dynamic table, as of its most recent refresh.
+# TYPE snowflake_dynamic_table_target_lag_seconds gauge
+snowflake_dynamic_table_target_lag_seconds{database_name="ANALYTICS",schema_name="MARTS",table_name="NEW_DT"} 60
+snowflake_dynamic_table_target_lag_seconds{database_name="ANALYTICS",schema_name="MARTS",table_name="ORDERS_DT"} 300
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_dynamic_table_lag_seconds", "snowflake_dynamic_table_last_refresh_duration_seconds",
+		"snowflake_dynamic_table_last_success_timestamp_seconds", "snowflake_dynamic_table_refreshes",
+		"snowflake_dynamic_table_target_lag_seconds"))
+}
+
+func TestCollector_collectDataTransferMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorDataTransfer)
+	mock.ExpectQuery(dataTransferMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"SOURCE_CLOUD", "SOURCE_REGION", "TARGET_CLOUD", "TARGET_REGION", "TRANSFER_TYPE", "BYTES_TRANSFERRED"}).
+			AddRow("AWS", "us-east-1", "AWS", "eu-west-1", "REPLICATION", 5368709120).
+			AddRow("AWS", "us-east-1", "GCP", "us-central1", "COPY", 1024)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_data_transfer_bytes Number of bytes transferred out of the account's region or cloud over the last 24 hours.
+# TYPE snowflake_data_transfer_bytes gauge
+snowflake_data_transfer_bytes{source_cloud="AWS",source_region="us-east-1",target_cloud="AWS",target_region="eu-west-1",transfer_type="REPLICATION"} 5.36870912e+09
+snowflake_data_transfer_bytes{source_cloud="AWS",source_region="us-east-1",target_cloud="GCP",target_region="us-central1",transfer_type="COPY"} 1024
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_data_transfer_bytes"))
+}
+
+func TestCollector_collectWarehouseEventMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorWarehouseEvents)
+	mock.ExpectQuery(warehouseEventMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "WAREHOUSE_ID", "EVENT_NAME", "COUNT"}).
+			AddRow("COMPUTE_WH", "1", "RESUME_WAREHOUSE", 12).
+			AddRow("COMPUTE_WH", "1", "SUSPEND_WAREHOUSE", 11).
+			AddRow("COMPUTE_WH", "1", "RESUME_CLUSTER", 3)).
+		RowsWillBeClosed()
+	mock.ExpectQuery(warehouseClusterMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"WAREHOUSE_NAME", "WAREHOUSE_ID", "CLUSTER_COUNT"}).
+			AddRow("COMPUTE_WH", "1", 3).
+			AddRow("LOAD_WH", "2", nil)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_warehouse_events Number of events of the type completed by the warehouse over the last 24 hours.
+# TYPE snowflake_warehouse_events gauge
+snowflake_warehouse_events{event_name="RESUME_CLUSTER",id="1",name="COMPUTE_WH"} 3
+snowflake_warehouse_events{event_name="RESUME_WAREHOUSE",id="1",name="COMPUTE_WH"} 12
+snowflake_warehouse_events{event_name="SUSPEND_WAREHOUSE",id="1",name="COMPUTE_WH"} 11
+# HELP snowflake_warehouse_observed_clusters Maximum number of clusters of the warehouse observed in its events over the last 24 hours.
+# TYPE snowflake_warehouse_observed_clusters gauge
+snowflake_warehouse_observed_clusters{id="1",name="COMPUTE_WH"} 3
+# HELP snowflake_exporter_collector_rows Number of rows returned by Snowflake during the most recent run of the collector.
+# TYPE snowflake_exporter_collector_rows gauge
+snowflake_exporter_collector_rows{collector="warehouse_events"} 5
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_warehouse_events", "snowflake_warehouse_observed_clusters", "snowflake_exporter_collector_rows"))
+}
+
+func TestCollector_collectWarehouseStateMetrics(t *testing.T) {
+	t.Run("Warehouses", func(t *testing.T) {
+		col, mock := newMockCollector(t, *ExampleConfig, collectorWarehouseState)
+		mock.ExpectQuery(warehouseStateMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"name", "state", "type", "size", "min_cluster_count", "max_cluster_count", "started_clusters",
+				"running", "queued", "is_default", "auto_suspend", "auto_resume", "owner",
+			}).
+				AddRow("COMPUTE_WH", "STARTED", "STANDARD", "X-Small", "1", "3", "2", "5", "1", "Y", "600", "true", "SYSADMIN").
+				AddRow("LOAD_WH", "SUSPENDED", "STANDARD", "Large", "1", "1", "0", "0", "0", "N", nil, "false", "SYSADMIN")).
+			RowsWillBeClosed()
+
+		expected := `
+# HELP snowflake_warehouse_auto_resume Whether the warehouse is resumed automatically when a query is submitted to it. 1 indicates enabled, 0 disabled.
+# TYPE snowflake_warehouse_auto_resume gauge
+snowflake_warehouse_auto_resume{name="COMPUTE_WH",size="X-Small"} 1
+snowflake_warehouse_auto_resume{name="LOAD_WH",size="Large"} 0
+# HELP snowflake_warehouse_auto_suspend_seconds Number of seconds of inactivity after which the warehouse is suspended. Not reported if the warehouse is never suspended automatically.
+# TYPE snowflake_warehouse_auto_suspend_seconds gauge
+snowflake_warehouse_auto_suspend_seconds{name="COMPUTE_WH",size="X-Small"} 600
+# HELP snowflake_warehouse_max_clusters Maximum number of clusters of the warehouse.
+# TYPE snowflake_warehouse_max_clusters gauge
+snowflake_warehouse_max_clusters{name="COMPUTE_WH",size="X-Small"} 3
+snowflake_warehouse_max_clusters{name="LOAD_WH",size="Large"} 1
+# HELP snowflake_warehouse_min_clusters Minimum number of clusters of the warehouse.
+# TYPE snowflake_warehouse_min_clusters gauge
+snowflake_warehouse_min_clusters{name="COMPUTE_WH",size="X-Small"} 1
+snowflake_warehouse_min_clusters{name="LOAD_WH",size="Large"} 1
+# HELP snowflake_warehouse_queued_queries Number of queries currently queued on the warehouse.
+# TYPE snowflake_warehouse_queued_queries gauge
+snowflake_warehouse_queued_queries{name="COMPUTE_WH",size="X-Small"} 1
+snowflake_warehouse_queued_queries{name="LOAD_WH",size="Large"} 0
+# HELP snowflake_warehouse_running_queries Number of queries currently running on the warehouse.
+# TYPE snowflake_warehouse_running_queries gauge
+snowflake_warehouse_running_queries{name="COMPUTE_WH",size="X-Small"} 5
+snowflake_warehouse_running_queries{name="LOAD_WH",size="Large"} 0
+# HELP snowflake_warehouse_started_clusters Number of clusters of the warehouse currently started.
+# TYPE snowflake_warehouse_started_clusters gauge
+snowflake_warehouse_started_clusters{name="COMPUTE_WH",size="X-Small"} 2
+snowflake_warehouse_started_clusters{name="LOAD_WH",size="Large"} 0
+# HELP snowflake_warehouse_state Whether the warehouse is currently in the state. 1 indicates the current state, 0 any other state.
+# TYPE snowflake_warehouse_state gauge
+snowflake_warehouse_state{name="COMPUTE_WH",size="X-Small",state="RESIZING"} 0
+snowflake_warehouse_state{name="COMPUTE_WH",size="X-Small",state="STARTED"} 1
+snowflake_warehouse_state{name="COMPUTE_WH",size="X-Small",state="SUSPENDED"} 0
+snowflake_warehouse_state{name="LOAD_WH",size="Large",state="RESIZING"} 0
+snowflake_warehouse_state{name="LOAD_WH",size="Large",state="STARTED"} 0
+snowflake_warehouse_state{name="LOAD_WH",size="Large",state="SUSPENDED"} 1
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+			"snowflake_warehouse_auto_resume", "snowflake_warehouse_auto_suspend_seconds", "snowflake_warehouse_max_clusters",
+			"snowflake_warehouse_min_clusters", "snowflake_warehouse_queued_queries", "snowflake_warehouse_running_queries",
+			"snowflake_warehouse_started_clusters", "snowflake_warehouse_state"))
+	})
+
+	t.Run("Missing column", func(t *testing.T) {
+		col, mock := newMockCollector(t, *ExampleConfig, collectorWarehouseState)
+		mock.ExpectQuery(warehouseStateMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "state"}).AddRow("COMPUTE_WH", "STARTED")).
+			RowsWillBeClosed()
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="warehouse_state"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+}
+
+func TestCollector_collectResourceMonitorMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorResourceMonitors)
+	mock.ExpectQuery(resourceMonitorMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"name", "credit_quota", "used_credits", "remaining_credits", "level", "frequency",
+			"notify_at", "suspend_at", "suspend_immediately_at", "owner",
+		}).
+			AddRow("ACCOUNT_MONITOR", "1000.00", "812.50", "187.50", "ACCOUNT", "MONTHLY", "50%,75%,90%", "100%", "110%", "ACCOUNTADMIN").
+			AddRow("WATCH_ONLY", nil, "12.00", nil, "WAREHOUSE", "DAILY", "50%", nil, nil, "ACCOUNTADMIN")).
+		RowsWillBeClosed()
+	mock.ExpectQuery(warehouseStateMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "state", "size", "resource_monitor"}).
+			AddRow("COMPUTE_WH", "STARTED", "X-Small", "WATCH_ONLY").
+			AddRow("LOAD_WH", "SUSPENDED", "Large", "null")).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_resource_monitor_credit_quota Number of credits the resource monitor allows to be used in its current interval.
+# TYPE snowflake_resource_monitor_credit_quota gauge
+snowflake_resource_monitor_credit_quota{name="ACCOUNT_MONITOR"} 1000
+# HELP snowflake_resource_monitor_remaining_credits Number of credits remaining in the current interval of the resource monitor.
+# TYPE snowflake_resource_monitor_remaining_credits gauge
+snowflake_resource_monitor_remaining_credits{name="ACCOUNT_MONITOR"} 187.5
+# HELP snowflake_resource_monitor_threshold_percent Percentage of the credit quota at which the resource monitor takes the action. A resource monitor may notify at several thresholds, each of which is its own series.
+# TYPE snowflake_resource_monitor_threshold_percent gauge
+snowflake_resource_monitor_threshold_percent{action="notify",name="ACCOUNT_MONITOR",threshold="50"} 50
+snowflake_resource_monitor_threshold_percent{action="notify",name="ACCOUNT_MONITOR",threshold="75"} 75
+snowflake_resource_monitor_threshold_percent{action="notify",name="ACCOUNT_MONITOR",threshold="90"} 90
+snowflake_resource_monitor_threshold_percent{action="notify",name="WATCH_ONLY",threshold="50"} 50
+snowflake_resource_monitor_threshold_percent{action="suspend",name="ACCOUNT_MONITOR",threshold="100"} 100
+snowflake_resource_monitor_threshold_percent{action="suspend_immediate",name="ACCOUNT_MONITOR",threshold="110"} 110
+# HELP snowflake_resource_monitor_used_credits Number of credits used in the current interval of the resource monitor.
+# TYPE snowflake_resource_monitor_used_credits gauge
+snowflake_resource_monitor_used_credits{name="ACCOUNT_MONITOR"} 812.5
+snowflake_resource_monitor_used_credits{name="WATCH_ONLY"} 12
+# HELP snowflake_resource_monitor_warehouse Whether the warehouse is assigned to the resource monitor. Always 1.
+# TYPE snowflake_resource_monitor_warehouse gauge
+snowflake_resource_monitor_warehouse{name="WATCH_ONLY",warehouse_name="COMPUTE_WH"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_resource_monitor_credit_quota", "snowflake_resource_monitor_remaining_credits", "snowflake_resource_monitor_threshold_percent",
+		"snowflake_resource_monitor_used_credits", "snowflake_resource_monitor_warehouse"))
+}
+
+func TestCollector_collectOrganizationUsageMetrics(t *testing.T) {
+	t.Run("Usage and rates", func(t *testing.T) {
+		col, mock := newMockCollector(t, *ExampleConfig, collectorOrganizationUsage)
+		mock.ExpectQuery(organizationUsageMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"ACCOUNT_NAME", "SERVICE_TYPE", "USAGE_TYPE", "CURRENCY", "USAGE_IN_CURRENCY"}).
+				AddRow("PROD", "WAREHOUSE_METERING", "compute", "USD", 412.5).
+				AddRow("PROD", "STORAGE", "storage", "USD", 23.1)).
+			RowsWillBeClosed()
+		mock.ExpectQuery(organizationRateMetricQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"ACCOUNT_NAME", "SERVICE_TYPE", "USAGE_TYPE", "CURRENCY", "EFFECTIVE_RATE"}).
+				AddRow("PROD", "WAREHOUSE_METERING", "compute", "USD", 2.75)).
+			RowsWillBeClosed()
+
+		expected := `
+# HELP snowflake_organization_effective_rate Price in the currency of a unit of the usage type for the account on the most recent day reported in ORGANIZATION_USAGE.
+# TYPE snowflake_organization_effective_rate gauge
+snowflake_organization_effective_rate{account_name="PROD",currency="USD",service_type="WAREHOUSE_METERING",usage_type="compute"} 2.75
+# HELP snowflake_organization_usage_in_currency Spend of the account in the currency on the most recent day reported in ORGANIZATION_USAGE.
+# TYPE snowflake_organization_usage_in_currency gauge
+snowflake_organization_usage_in_currency{account_name="PROD",currency="USD",service_type="STORAGE",usage_type="storage"} 23.1
+snowflake_organization_usage_in_currency{account_name="PROD",currency="USD",service_type="WAREHOUSE_METERING",usage_type="compute"} 412.5
+# HELP snowflake_exporter_collector_rows Number of rows returned by Snowflake during the most recent run of the collector.
+# TYPE snowflake_exporter_collector_rows gauge
+snowflake_exporter_collector_rows{collector="organization_usage"} 3
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+			"snowflake_organization_effective_rate", "snowflake_organization_usage_in_currency", "snowflake_exporter_collector_rows"))
+	})
+
+	t.Run("Missing privileges", func(t *testing.T) {
+		col, mock := newMockCollector(t, *ExampleConfig, collectorOrganizationUsage)
+		mock.ExpectQuery(organizationUsageMetricQuery).
+			WillReturnError(errors.New("Object 'ORGANIZATION_USAGE.USAGE_IN_CURRENCY_DAILY' does not exist or not authorized."))
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="organization_usage"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+}
+
+func TestCollector_collectOrganizationBalanceMetrics(t *testing.T) {
+	col, mock := newMockCollector(t, *ExampleConfig, collectorOrganizationBalance)
+	mock.ExpectQuery(organizationBalanceMetricQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"CONTRACT_NUMBER", "CURRENCY", "CAPACITY_BALANCE", "FREE_USAGE_BALANCE", "ROLLOVER_BALANCE", "ON_DEMAND_CONSUMPTION_BALANCE"}).
+			AddRow("1234", "USD", 87500, 0, 2500, nil)).
+		RowsWillBeClosed()
+
+	expected := `
+# HELP snowflake_organization_remaining_balance Remaining balance of the contract in the currency at the end of the most recent day reported in ORGANIZATION_USAGE.
+# TYPE snowflake_organization_remaining_balance gauge
+snowflake_organization_remaining_balance{balance_type="capacity",contract_number="1234",currency="USD"} 87500
+snowflake_organization_remaining_balance{balance_type="free_usage",contract_number="1234",currency="USD"} 0
+snowflake_organization_remaining_balance{balance_type="rollover",contract_number="1234",currency="USD"} 2500
+# HELP snowflake_exporter_collector_rows Number of rows returned by Snowflake during the most recent run of the collector.
+# TYPE snowflake_exporter_collector_rows gauge
+snowflake_exporter_collector_rows{collector="organization_balance"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expected),
+		"snowflake_organization_remaining_balance", "snowflake_exporter_collector_rows"))
+}
@@ -16,48 +16,225 @@ package collector
 
 import (
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/snowflakedb/gosnowflake/v2"
 	"github.com/youmark/pkcs8"
+	"go.yaml.in/yaml/v2"
 )
 
 // Config holds the connection and authentication setting.
 // Use Validate to check required fields before constructing a
 // connection string.
 type Config struct {
-	AccountName        string
-	Username           string
-	Password           string
-	Role               string
-	Warehouse          string
-	PrivateKeyPath     string
-	PrivateKeyPassword string
-	PrivateKey         *rsa.PrivateKey
-	ExcludeDeleted     bool
-	EnableTracing      bool
+	AccountName        string          `yaml:"account_name"`
+	Username           string          `yaml:"username"`
+	Password           string          `yaml:"password"`
+	Role               string          `yaml:"role"`
+	Warehouse          string          `yaml:"warehouse"`
+	PrivateKeyPath     string          `yaml:"private_key_path"`
+	PrivateKeyPassword string          `yaml:"private_key_password"`
+	PrivateKey         *rsa.PrivateKey `yaml:"-"`
+	ExcludeDeleted     bool            `yaml:"exclude_deleted_tables"`
+	EnableTracing      bool            `yaml:"enable_tracing"`
+	// RefreshInterval is how often metrics are refreshed in the background.
+	// If zero, Snowflake is queried on every scrape.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// Collectors maps collector names to whether they are enabled.
+	// Collectors missing from the map use their default.
+	Collectors map[string]bool `yaml:"collectors"`
+	// CollectorIntervals maps collector names to how often their metrics are refreshed.
+	// Collectors missing from the map are refreshed every RefreshInterval.
+	CollectorIntervals map[string]time.Duration `yaml:"collector_intervals"`
+	// QueryTimeout bounds how long each collector's queries may run. If zero, queries are only
+	// bounded by the scrape timeout.
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// CollectorTimeouts maps collector names to how long their queries may run.
+	// Collectors missing from the map use QueryTimeout.
+	CollectorTimeouts map[string]time.Duration `yaml:"collector_timeouts"`
+	// CustomQueries are user-defined queries whose results are exported as metrics.
+	CustomQueries []CustomQuery `yaml:"custom_queries"`
+	// Modules maps module names to the credentials used when probing an account with that module.
+	Modules map[string]Module `yaml:"modules"`
+	// QueryHistoryLabels are the labels by which the query_history collector splits its metrics.
+	// If empty, all of warehouse_name, user_name, role_name, query_type, and execution_status are used.
+	QueryHistoryLabels []string `yaml:"query_history_labels"`
+	// QueryLatencyBuckets are the upper bounds in seconds of the buckets of the query_latency collector's
+	// histograms. If empty, buckets ranging from 100ms to 1h are used.
+	QueryLatencyBuckets []float64 `yaml:"query_latency_buckets"`
+	// MaxOpenConns is the maximum number of open connections to Snowflake. If zero, the
+	// number of connections is not limited.
+	MaxOpenConns int `yaml:"max_open_connections"`
+	// MaxIdleConns is the maximum number of idle connections kept open between refreshes.
+	// If zero, it defaults to MaxOpenConns, or to the number of collectors run concurrently
+	// if MaxOpenConns is zero.
+	MaxIdleConns int `yaml:"max_idle_connections"`
+	// ConnMaxLifetime is how long a connection may be reused before it is closed. If zero,
+	// connections are reused forever.
+	ConnMaxLifetime time.Duration `yaml:"connection_max_lifetime"`
+	// ClientSessionKeepAlive keeps the Snowflake sessions of idle connections alive, so that
+	// connections are not logged in again between refreshes.
+	ClientSessionKeepAlive bool `yaml:"client_session_keep_alive"`
+	// Accounts are collected together, with their metrics labeled by account. If set,
+	// AccountName must be empty.
+	Accounts []Account `yaml:"accounts"`
+}
+
+// Account holds the name and credentials of one of several accounts to collect. Credentials left
+// empty use the value of the top-level Config.
+type Account struct {
+	AccountName string `yaml:"account_name"`
+	Module      `yaml:",inline"`
+}
+
+// Module holds the credentials used to probe an account. Fields left empty use the
+// value of the top-level Config.
+type Module struct {
+	Username           string `yaml:"username"`
+	Password           string `yaml:"password"`
+	PrivateKeyPath     string `yaml:"private_key_path"`
+	PrivateKeyPassword string `yaml:"private_key_password"`
+	Role               string `yaml:"role"`
+	Warehouse          string `yaml:"warehouse"`
 }
 
 var (
-	errNoAccountName  = errors.New("account_name must be specified")
-	errNoRole         = errors.New("role must be specified")
-	errNoWarehouse    = errors.New("warehouse must be specified")
-	errNoUsername     = errors.New("username must be specified")
-	errNoAuth         = errors.New("password or private_key must be specified")
-	errDecodingPEM    = errors.New("error occurred while decoding private key PEM block")
-	errFileNotRSAType = errors.New("type assertion failed, expected type *rsa.PrivateKey")
+	errNoAccountName    = errors.New("account_name must be specified")
+	errNoRole           = errors.New("role must be specified")
+	errNoWarehouse      = errors.New("warehouse must be specified")
+	errNoUsername       = errors.New("username must be specified")
+	errNoAuth           = errors.New("password or private_key must be specified")
+	errRefreshNegative  = errors.New("refresh_interval must not be negative")
+	errUnknownCollector = errors.New("unknown collector")
+	errIntervalNegative = errors.New("collector interval must not be negative")
+	errTimeoutNegative  = errors.New("query timeout must not be negative")
+	errNoTarget         = errors.New("probe target must be specified")
+	errUnknownModule    = errors.New("unknown module")
+	errAccountsConflict = errors.New("account_name and accounts must not both be specified")
+	errDuplicateAccount = errors.New("account is specified more than once")
+	errPoolNegative     = errors.New("connection pool limits must not be negative")
+	errUnknownLabel     = errors.New("unknown query history label")
+	errBucketsInvalid   = errors.New("query latency buckets must be positive and increasing")
+	errDecodingPEM      = errors.New("error occurred while decoding private key PEM block")
+	errFileNotRSAType   = errors.New("type assertion failed, expected type *rsa.PrivateKey")
 )
 
+// LoadConfig reads a Config from a YAML file.
+// The returned config is not validated.
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
 // Validate returns an error if any required Config field is missing.
+// The account and credentials may be omitted if modules or accounts are defined.
 func (c Config) Validate() error {
-	if c.AccountName == "" {
-		return errNoAccountName
+	if c.AccountName != "" && len(c.Accounts) > 0 {
+		return errAccountsConflict
+	}
+
+	if c.AccountName != "" || (len(c.Modules) == 0 && len(c.Accounts) == 0) {
+		if c.AccountName == "" {
+			return errNoAccountName
+		}
+		if err := c.validateCredentials(); err != nil {
+			return err
+		}
+	}
+
+	accounts := map[string]bool{}
+	for _, a := range c.Accounts {
+		if a.AccountName == "" {
+			return errNoAccountName
+		}
+		if accounts[a.AccountName] {
+			return fmt.Errorf("%w: %s", errDuplicateAccount, a.AccountName)
+		}
+		accounts[a.AccountName] = true
+
+		if err := c.withModule(a.Module).validateCredentials(); err != nil {
+			return fmt.Errorf("account %s: %w", a.AccountName, err)
+		}
+	}
+
+	for name, m := range c.Modules {
+		if err := c.withModule(m).validateCredentials(); err != nil {
+			return fmt.Errorf("module %s: %w", name, err)
+		}
+	}
+
+	if c.RefreshInterval < 0 {
+		return errRefreshNegative
+	}
+
+	for name := range c.Collectors {
+		if _, ok := collectorDefaults[name]; !ok {
+			return fmt.Errorf("%w: %s", errUnknownCollector, name)
+		}
+	}
+
+	for name, interval := range c.CollectorIntervals {
+		if _, ok := collectorDefaults[name]; !ok {
+			return fmt.Errorf("%w: %s", errUnknownCollector, name)
+		}
+		if interval < 0 {
+			return fmt.Errorf("%w: %s", errIntervalNegative, name)
+		}
+	}
+
+	if c.QueryTimeout < 0 {
+		return errTimeoutNegative
+	}
+
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 || c.ConnMaxLifetime < 0 {
+		return errPoolNegative
+	}
+
+	for name, timeout := range c.CollectorTimeouts {
+		if _, ok := collectorDefaults[name]; !ok {
+			return fmt.Errorf("%w: %s", errUnknownCollector, name)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("%w: %s", errTimeoutNegative, name)
+		}
+	}
+
+	for _, label := range c.QueryHistoryLabels {
+		if !slices.ContainsFunc(queryHistoryDimensions, func(d queryHistoryDimension) bool { return d.label == label }) {
+			return fmt.Errorf("%w: %s", errUnknownLabel, label)
+		}
+	}
+
+	for i, bucket := range c.QueryLatencyBuckets {
+		if bucket <= 0 || (i > 0 && bucket <= c.QueryLatencyBuckets[i-1]) {
+			return errBucketsInvalid
+		}
+	}
+
+	if err := validateCustomQueries(c.CustomQueries); err != nil {
+		return err
 	}
 
+	return nil
+}
+
+// validateCredentials returns an error if any field required to connect to an account is missing.
+func (c Config) validateCredentials() error {
 	if c.Username == "" {
 		return errNoUsername
 	}
@@ -77,6 +254,152 @@ func (c Config) Validate() error {
 	return nil
 }
 
+// withModule returns a copy of the config whose credentials are overridden by the module's.
+func (c Config) withModule(m Module) Config {
+	if m.Username != "" {
+		c.Username = m.Username
+	}
+	if m.Password != "" || m.PrivateKeyPath != "" {
+		// A module's password or private key replaces both, so that they are never mixed.
+		c.Password = m.Password
+		c.PrivateKeyPath = m.PrivateKeyPath
+		c.PrivateKeyPassword = m.PrivateKeyPassword
+	}
+	if m.Role != "" {
+		c.Role = m.Role
+	}
+	if m.Warehouse != "" {
+		c.Warehouse = m.Warehouse
+	}
+	return c
+}
+
+// ProbeConfig returns the config used to probe the target account with the named module.
+// If module is empty, the top-level credentials are used. The returned config is validated,
+// and queries Snowflake on every scrape.
+func (c Config) ProbeConfig(target, module string) (*Config, error) {
+	if target == "" {
+		return nil, errNoTarget
+	}
+
+	probe := c
+	if module != "" {
+		m, ok := c.Modules[module]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errUnknownModule, module)
+		}
+		probe = c.withModule(m)
+	}
+	probe.AccountName = target
+	probe.Modules = nil
+	probe.Accounts = nil
+	probe.RefreshInterval = 0
+	probe.CollectorIntervals = nil
+
+	if err := probe.Validate(); err != nil {
+		return nil, err
+	}
+	return &probe, nil
+}
+
+// AccountConfigs returns the config of each account in Accounts. The returned configs are
+// only valid if the config is valid.
+func (c Config) AccountConfigs() []*Config {
+	configs := make([]*Config, 0, len(c.Accounts))
+	for _, a := range c.Accounts {
+		account := c.withModule(a.Module)
+		account.AccountName = a.AccountName
+		account.Modules = nil
+		account.Accounts = nil
+		configs = append(configs, &account)
+	}
+	return configs
+}
+
+// queryHistoryDimensions returns the dimensions of the configured query history labels.
+func (c Config) queryHistoryDimensions() []queryHistoryDimension {
+	var dimensions []queryHistoryDimension
+	for _, d := range queryHistoryDimensions {
+		if len(c.QueryHistoryLabels) == 0 || slices.Contains(c.QueryHistoryLabels, d.label) {
+			dimensions = append(dimensions, d)
+		}
+	}
+	return dimensions
+}
+
+// queryLatencyBuckets returns the upper bounds in seconds of the query latency histograms' buckets.
+func (c Config) queryLatencyBuckets() []float64 {
+	if len(c.QueryLatencyBuckets) > 0 {
+		return c.QueryLatencyBuckets
+	}
+	return defaultQueryLatencyBuckets
+}
+
+// connectionSettings holds the fields of a Config which determine how to connect to Snowflake.
+type connectionSettings struct {
+	accountName            string
+	username               string
+	password               string
+	role                   string
+	warehouse              string
+	privateKeyPath         string
+	privateKeyPassword     string
+	privateKeyDigest       [sha256.Size]byte
+	enableTracing          bool
+	clientSessionKeepAlive bool
+}
+
+// connectionSettings returns the fields of the config which determine how to connect to Snowflake,
+// so that a connection is only re-established when they change. The private key file is read, so
+// that a key rotated in place at the same path is also picked up.
+func (c Config) connectionSettings() connectionSettings {
+	s := connectionSettings{
+		accountName:            c.AccountName,
+		username:               c.Username,
+		password:               c.Password,
+		role:                   c.Role,
+		warehouse:              c.Warehouse,
+		privateKeyPath:         c.PrivateKeyPath,
+		privateKeyPassword:     c.PrivateKeyPassword,
+		enableTracing:          c.EnableTracing,
+		clientSessionKeepAlive: c.ClientSessionKeepAlive,
+	}
+	// A key file which cannot be read fails when connecting, so its error is not reported here
+	if c.PrivateKeyPath != "" {
+		if pk, err := os.ReadFile(c.PrivateKeyPath); err == nil {
+			s.privateKeyDigest = sha256.Sum256(pk)
+		}
+	}
+	return s
+}
+
+// collectorEnabled returns whether the named collector should be run.
+func (c Config) collectorEnabled(name string) bool {
+	if name == collectorDeletedTables && c.ExcludeDeleted {
+		return false
+	}
+	if enabled, ok := c.Collectors[name]; ok {
+		return enabled
+	}
+	return collectorDefaults[name]
+}
+
+// collectorInterval returns how often the named collector should be refreshed.
+func (c Config) collectorInterval(name string) time.Duration {
+	if interval := c.CollectorIntervals[name]; interval > 0 {
+		return interval
+	}
+	return c.RefreshInterval
+}
+
+// collectorTimeout returns how long the queries of the named collector may run.
+func (c Config) collectorTimeout(name string) time.Duration {
+	if timeout := c.CollectorTimeouts[name]; timeout > 0 {
+		return timeout
+	}
+	return c.QueryTimeout
+}
+
 // decryptPrivateKey returns a RSA private key from the PrivateKeyPath and PrivateKeyPassword fields
 // of the config.
 // Assumes that the private key is encrypted in PKCS #8 syntax, as is recommended by Snowflake
@@ -132,6 +455,11 @@ func (c Config) snowflakeConnectionString() (string, error) {
 		sf.Tracing = "trace" //nolint:staticcheck
 	}
 
+	if c.ClientSessionKeepAlive {
+		keepAlive := "true"
+		sf.Params = map[string]*string{"client_session_keep_alive": &keepAlive}
+	}
+
 	if c.Password != "" {
 		// password authentication
 		sf.Password = c.Password
@@ -15,7 +15,10 @@
 package collector
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -76,6 +79,102 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expectedErr: errNoWarehouse,
 		},
+		{
+			name: "Negative refresh interval",
+			inputConfig: Config{
+				AccountName:     "some_account",
+				Username:        "some_user",
+				Password:        "some_pass",
+				Role:            "ACCOUNTADMIN",
+				Warehouse:       "ACCOUNT_WH",
+				RefreshInterval: -time.Minute,
+			},
+			expectedErr: errRefreshNegative,
+		},
+		{
+			name: "Unknown collector",
+			inputConfig: Config{
+				AccountName: "some_account",
+				Username:    "some_user",
+				Password:    "some_pass",
+				Role:        "ACCOUNTADMIN",
+				Warehouse:   "ACCOUNT_WH",
+				Collectors:  map[string]bool{"not_a_collector": true},
+			},
+			expectedErr: errUnknownCollector,
+		},
+		{
+			name: "Negative collector interval",
+			inputConfig: Config{
+				AccountName:        "some_account",
+				Username:           "some_user",
+				Password:           "some_pass",
+				Role:               "ACCOUNTADMIN",
+				Warehouse:          "ACCOUNT_WH",
+				CollectorIntervals: map[string]time.Duration{collectorStorage: -time.Hour},
+			},
+			expectedErr: errIntervalNegative,
+		},
+		{
+			name: "Negative query timeout",
+			inputConfig: Config{
+				AccountName:  "some_account",
+				Username:     "some_user",
+				Password:     "some_pass",
+				Role:         "ACCOUNTADMIN",
+				Warehouse:    "ACCOUNT_WH",
+				QueryTimeout: -time.Minute,
+			},
+			expectedErr: errTimeoutNegative,
+		},
+		{
+			name: "Negative connection pool limit",
+			inputConfig: Config{
+				AccountName:  "some_account",
+				Username:     "some_user",
+				Password:     "some_pass",
+				Role:         "ACCOUNTADMIN",
+				Warehouse:    "ACCOUNT_WH",
+				MaxIdleConns: -1,
+			},
+			expectedErr: errPoolNegative,
+		},
+		{
+			name: "Unknown query history label",
+			inputConfig: Config{
+				AccountName:        "some_account",
+				Username:           "some_user",
+				Password:           "some_pass",
+				Role:               "ACCOUNTADMIN",
+				Warehouse:          "ACCOUNT_WH",
+				QueryHistoryLabels: []string{labelWarehouseName, "query_text"},
+			},
+			expectedErr: errUnknownLabel,
+		},
+		{
+			name: "Unsorted query latency buckets",
+			inputConfig: Config{
+				AccountName:         "some_account",
+				Username:            "some_user",
+				Password:            "some_pass",
+				Role:                "ACCOUNTADMIN",
+				Warehouse:           "ACCOUNT_WH",
+				QueryLatencyBuckets: []float64{1, 10, 5},
+			},
+			expectedErr: errBucketsInvalid,
+		},
+		{
+			name: "Non-positive query latency bucket",
+			inputConfig: Config{
+				AccountName:         "some_account",
+				Username:            "some_user",
+				Password:            "some_pass",
+				Role:                "ACCOUNTADMIN",
+				Warehouse:           "ACCOUNT_WH",
+				QueryLatencyBuckets: []float64{0, 1},
+			},
+			expectedErr: errBucketsInvalid,
+		},
 		{
 			name: "Valid config - password",
 			inputConfig: Config{
@@ -107,13 +206,100 @@ func TestConfig_Validate(t *testing.T) {
 				PrivateKeyPath: "some/path/rsa_key.p8",
 			},
 		},
+		{
+			name: "Valid config - modules only",
+			inputConfig: Config{
+				Role: "ACCOUNTADMIN",
+				Modules: map[string]Module{
+					"default": {Username: "some_user", Password: "some_pass", Warehouse: "ACCOUNT_WH"},
+				},
+			},
+		},
+		{
+			name: "Module without credentials",
+			inputConfig: Config{
+				Role:      "ACCOUNTADMIN",
+				Warehouse: "ACCOUNT_WH",
+				Modules: map[string]Module{
+					"default": {Username: "some_user"},
+				},
+			},
+			expectedErr: errNoAuth,
+		},
+		{
+			name: "Modules with partial top-level account",
+			inputConfig: Config{
+				AccountName: "some_account",
+				Role:        "ACCOUNTADMIN",
+				Modules: map[string]Module{
+					"default": {Username: "some_user", Password: "some_pass", Warehouse: "ACCOUNT_WH"},
+				},
+			},
+			expectedErr: errNoUsername,
+		},
+		{
+			name: "Valid config - accounts",
+			inputConfig: Config{
+				Username:  "some_user",
+				Password:  "some_pass",
+				Role:      "ACCOUNTADMIN",
+				Warehouse: "ACCOUNT_WH",
+				Accounts: []Account{
+					{AccountName: "some_account"},
+					{AccountName: "other_account", Module: Module{Username: "other_user", Password: "other_pass"}},
+				},
+			},
+		},
+		{
+			name: "Account name and accounts",
+			inputConfig: Config{
+				AccountName: "some_account",
+				Username:    "some_user",
+				Password:    "some_pass",
+				Role:        "ACCOUNTADMIN",
+				Warehouse:   "ACCOUNT_WH",
+				Accounts:    []Account{{AccountName: "other_account"}},
+			},
+			expectedErr: errAccountsConflict,
+		},
+		{
+			name: "Account without name",
+			inputConfig: Config{
+				Username:  "some_user",
+				Password:  "some_pass",
+				Role:      "ACCOUNTADMIN",
+				Warehouse: "ACCOUNT_WH",
+				Accounts:  []Account{{Module: Module{Role: "READER"}}},
+			},
+			expectedErr: errNoAccountName,
+		},
+		{
+			name: "Duplicate account",
+			inputConfig: Config{
+				Username:  "some_user",
+				Password:  "some_pass",
+				Role:      "ACCOUNTADMIN",
+				Warehouse: "ACCOUNT_WH",
+				Accounts:  []Account{{AccountName: "some_account"}, {AccountName: "some_account"}},
+			},
+			expectedErr: errDuplicateAccount,
+		},
+		{
+			name: "Account without credentials",
+			inputConfig: Config{
+				Role:      "ACCOUNTADMIN",
+				Warehouse: "ACCOUNT_WH",
+				Accounts:  []Account{{AccountName: "some_account", Module: Module{Username: "some_user"}}},
+			},
+			expectedErr: errNoAuth,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			err := tc.inputConfig.Validate()
 			if tc.expectedErr != nil {
-				require.Equal(t, tc.expectedErr, err)
+				require.ErrorIs(t, err, tc.expectedErr)
 			} else {
 				require.Nil(t, err)
 			}
@@ -121,6 +307,157 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestLoadConfig(t *testing.T) {
+	t.Run("Valid file", func(t *testing.T) {
+		config, err := LoadConfig(filepath.Join("testdata", "config.yml"))
+		require.NoError(t, err)
+		require.NoError(t, config.Validate())
+
+		require.Equal(t, &Config{
+			AccountName:        "some-account",
+			Username:           "some-user",
+			PrivateKeyPath:     "some/path/rsa_key.p8",
+			PrivateKeyPassword: "some-password",
+			Role:               "ACCOUNTADMIN",
+			Warehouse:          "some-warehouse",
+			ExcludeDeleted:     true,
+			RefreshInterval:    15 * time.Minute,
+			QueryTimeout:       2 * time.Minute,
+			Collectors:         map[string]bool{collectorTableStorage: false},
+			CollectorIntervals: map[string]time.Duration{collectorStorage: 6 * time.Hour},
+			CollectorTimeouts:  map[string]time.Duration{collectorAutoClustering: 10 * time.Minute},
+			CustomQueries: []CustomQuery{
+				{
+					Name:   "custom_task_failures",
+					Type:   customQueryTypeCounter,
+					Query:  "SELECT count(*) AS FAILURES FROM ACCOUNT_USAGE.TASK_HISTORY WHERE STATE = 'FAILED';",
+					Values: []string{"failures"},
+				},
+			},
+		}, config)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, os.WriteFile(path, []byte("account: some-account\n"), 0o600))
+
+		_, err := LoadConfig(path)
+		require.ErrorContains(t, err, "field account not found")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join("testdata", "does_not_exist.yml"))
+		require.ErrorContains(t, err, "failed to read config file")
+	})
+}
+
+func TestConfig_ProbeConfig(t *testing.T) {
+	config := Config{
+		Username:           "some_user",
+		PrivateKeyPath:     "some/path/rsa_key.p8",
+		PrivateKeyPassword: "some-password",
+		Role:               "ACCOUNTADMIN",
+		Warehouse:          "ACCOUNT_WH",
+		RefreshInterval:    15 * time.Minute,
+		CollectorIntervals: map[string]time.Duration{collectorStorage: 6 * time.Hour},
+		Modules: map[string]Module{
+			"reader": {Username: "reader", Password: "reader_pass", Role: "READER"},
+		},
+	}
+
+	t.Run("top-level credentials", func(t *testing.T) {
+		probe, err := config.ProbeConfig("some_account", "")
+		require.NoError(t, err)
+		require.Equal(t, "some_account", probe.AccountName)
+		require.Equal(t, "some_user", probe.Username)
+		require.Equal(t, "some/path/rsa_key.p8", probe.PrivateKeyPath)
+		require.Equal(t, "ACCOUNTADMIN", probe.Role)
+		require.Zero(t, probe.RefreshInterval)
+		require.Nil(t, probe.CollectorIntervals)
+		require.Nil(t, probe.Modules)
+	})
+
+	t.Run("module credentials", func(t *testing.T) {
+		probe, err := config.ProbeConfig("some_account", "reader")
+		require.NoError(t, err)
+		require.Equal(t, "some_account", probe.AccountName)
+		require.Equal(t, "reader", probe.Username)
+		require.Equal(t, "reader_pass", probe.Password)
+		require.Empty(t, probe.PrivateKeyPath)
+		require.Empty(t, probe.PrivateKeyPassword)
+		require.Equal(t, "READER", probe.Role)
+		require.Equal(t, "ACCOUNT_WH", probe.Warehouse)
+	})
+
+	t.Run("no target", func(t *testing.T) {
+		_, err := config.ProbeConfig("", "reader")
+		require.ErrorIs(t, err, errNoTarget)
+	})
+
+	t.Run("unknown module", func(t *testing.T) {
+		_, err := config.ProbeConfig("some_account", "writer")
+		require.ErrorIs(t, err, errUnknownModule)
+	})
+}
+
+func TestConfig_AccountConfigs(t *testing.T) {
+	config := Config{
+		Username:        "some_user",
+		Password:        "some_pass",
+		Role:            "ACCOUNTADMIN",
+		Warehouse:       "ACCOUNT_WH",
+		RefreshInterval: 15 * time.Minute,
+		Accounts: []Account{
+			{AccountName: "some_account"},
+			{AccountName: "other_account", Module: Module{Username: "other_user", PrivateKeyPath: "some/path/rsa_key.p8"}},
+		},
+	}
+
+	configs := config.AccountConfigs()
+	require.Len(t, configs, 2)
+
+	require.Equal(t, "some_account", configs[0].AccountName)
+	require.Equal(t, "some_user", configs[0].Username)
+	require.Equal(t, "some_pass", configs[0].Password)
+	require.Equal(t, 15*time.Minute, configs[0].RefreshInterval)
+	require.Nil(t, configs[0].Accounts)
+
+	require.Equal(t, "other_account", configs[1].AccountName)
+	require.Equal(t, "other_user", configs[1].Username)
+	require.Empty(t, configs[1].Password)
+	require.Equal(t, "some/path/rsa_key.p8", configs[1].PrivateKeyPath)
+	require.Equal(t, "ACCOUNTADMIN", configs[1].Role)
+	require.NoError(t, configs[1].Validate())
+}
+
+func TestConfig_collectorEnabled(t *testing.T) {
+	config := Config{Collectors: map[string]bool{collectorTableStorage: false}}
+	require.False(t, config.collectorEnabled(collectorTableStorage))
+	require.True(t, config.collectorEnabled(collectorStorage))
+	require.True(t, config.collectorEnabled(collectorDeletedTables))
+
+	config.ExcludeDeleted = true
+	require.False(t, config.collectorEnabled(collectorDeletedTables))
+}
+
+func TestConfig_collectorInterval(t *testing.T) {
+	config := Config{
+		RefreshInterval:    time.Minute,
+		CollectorIntervals: map[string]time.Duration{collectorStorage: 6 * time.Hour},
+	}
+	require.Equal(t, 6*time.Hour, config.collectorInterval(collectorStorage))
+	require.Equal(t, time.Minute, config.collectorInterval(collectorLogins))
+}
+
+func TestConfig_collectorTimeout(t *testing.T) {
+	config := Config{
+		QueryTimeout:      time.Minute,
+		CollectorTimeouts: map[string]time.Duration{collectorTableStorage: 10 * time.Minute},
+	}
+	require.Equal(t, 10*time.Minute, config.collectorTimeout(collectorTableStorage))
+	require.Equal(t, time.Minute, config.collectorTimeout(collectorLogins))
+}
+
 func TestConfig_snowflakeConnectionString(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -173,6 +510,18 @@ func TestConfig_snowflakeConnectionString(t *testing.T) {
 			},
 			expectedString: `some%25user:some+pass@some%account.snowflakecomputing.com:443?database=SNOWFLAKE&ocspFailOpen=true&role=ACCOUNTADMIN%21&validateDefaultParameters=true&warehouse=some%21warehouse`,
 		},
+		{
+			name: "Valid config with client session keep-alive",
+			inputConfig: Config{
+				AccountName:            "some-account",
+				Username:               "some-user",
+				Password:               "some-pass",
+				Role:                   "ACCOUNTADMIN",
+				Warehouse:              "some-warehouse",
+				ClientSessionKeepAlive: true,
+			},
+			expectedString: "some-user:some-pass@some-account.snowflakecomputing.com:443?client_session_keep_alive=true&database=SNOWFLAKE&ocspFailOpen=true&role=ACCOUNTADMIN&validateDefaultParameters=true&warehouse=some-warehouse",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,267 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"go.yaml.in/yaml/v2"
+)
+
+const (
+	customQueryTypeGauge   = "gauge"
+	customQueryTypeCounter = "counter"
+
+	// customCollectorPrefix prefixes the collector name of custom queries in the exporter's own metrics.
+	customCollectorPrefix = "custom."
+)
+
+// CustomQuery defines a metric exported from the results of a user-provided SQL query.
+type CustomQuery struct {
+	// Name is the full name of the exported metric.
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+	// Type is either "gauge" or "counter". Defaults to "gauge".
+	Type  string `yaml:"type"`
+	Query string `yaml:"query"`
+	// Labels are the columns whose values are used as labels.
+	Labels []string `yaml:"labels"`
+	// Values are the columns whose values are exported as samples.
+	Values []string `yaml:"values"`
+	// ValueLabel is the name of the label holding the value column of each sample.
+	// It is required if more than one value column is given.
+	ValueLabel string `yaml:"value_label"`
+	// Interval is how often the query is run. If zero, the config's RefreshInterval is used.
+	Interval time.Duration `yaml:"interval"`
+}
+
+var (
+	errCustomNoName          = errors.New("custom query name must be specified")
+	errCustomInvalidName     = errors.New("custom query name is not a valid metric name")
+	errCustomReservedName    = errors.New("custom query name must not start with " + namespace + "_")
+	errCustomDuplicateName   = errors.New("custom query name is used more than once")
+	errCustomNoQuery         = errors.New("custom query must specify a query")
+	errCustomInvalidType     = errors.New("custom query type must be gauge or counter")
+	errCustomNoValues        = errors.New("custom query must specify at least one value column")
+	errCustomNoValueLabel    = errors.New("custom query with multiple value columns must specify a value_label")
+	errCustomInvalidLabel    = errors.New("custom query label is not a valid label name")
+	errCustomReservedLabel   = errors.New("custom query label is reserved")
+	errCustomDuplicateLabel  = errors.New("custom query label is used more than once")
+	errCustomIntervalInvalid = errors.New("custom query interval must not be negative")
+	errCustomDuplicateRow    = errors.New("custom query returned more than one row with the same label values")
+)
+
+// customQueriesFile is the structure of a custom queries file.
+type customQueriesFile struct {
+	CustomQueries []CustomQuery `yaml:"custom_queries"`
+}
+
+// LoadCustomQueries reads custom query definitions from the custom_queries key of a YAML file.
+func LoadCustomQueries(path string) ([]CustomQuery, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom queries file %s: %w", path, err)
+	}
+
+	var f customQueriesFile
+	if err := yaml.UnmarshalStrict(content, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse custom queries file %s: %w", path, err)
+	}
+	return f.CustomQueries, nil
+}
+
+// validateCustomQueries returns an error if any custom query is invalid.
+func validateCustomQueries(queries []CustomQuery) error {
+	names := map[string]bool{}
+	for _, q := range queries {
+		if q.Name == "" {
+			return errCustomNoName
+		}
+		if !model.IsValidLegacyMetricName(q.Name) {
+			return fmt.Errorf("%w: %s", errCustomInvalidName, q.Name)
+		}
+		// Names in the exporter's namespace may collide with the built-in metrics
+		if strings.HasPrefix(q.Name, namespace+"_") {
+			return fmt.Errorf("%w: %s", errCustomReservedName, q.Name)
+		}
+		if names[q.Name] {
+			return fmt.Errorf("%w: %s", errCustomDuplicateName, q.Name)
+		}
+		names[q.Name] = true
+
+		if strings.TrimSpace(q.Query) == "" {
+			return fmt.Errorf("%w: %s", errCustomNoQuery, q.Name)
+		}
+		if q.Type != "" && q.Type != customQueryTypeGauge && q.Type != customQueryTypeCounter {
+			return fmt.Errorf("%w: %s", errCustomInvalidType, q.Name)
+		}
+		if len(q.Values) == 0 {
+			return fmt.Errorf("%w: %s", errCustomNoValues, q.Name)
+		}
+		if len(q.Values) > 1 && q.ValueLabel == "" {
+			return fmt.Errorf("%w: %s", errCustomNoValueLabel, q.Name)
+		}
+		labels := map[string]bool{}
+		for _, label := range q.labelNames() {
+			if !model.LegacyValidation.IsValidLabelName(label) {
+				return fmt.Errorf("%w: %s: %s", errCustomInvalidLabel, q.Name, label)
+			}
+			// The account label is added to every metric when collecting multiple accounts
+			if label == labelAccount || strings.HasPrefix(label, model.ReservedLabelPrefix) {
+				return fmt.Errorf("%w: %s: %s", errCustomReservedLabel, q.Name, label)
+			}
+			if labels[label] {
+				return fmt.Errorf("%w: %s: %s", errCustomDuplicateLabel, q.Name, label)
+			}
+			labels[label] = true
+		}
+		if q.Interval < 0 {
+			return fmt.Errorf("%w: %s", errCustomIntervalInvalid, q.Name)
+		}
+	}
+	return nil
+}
+
+// labelNames returns the names of the labels of the metric exported by the query.
+func (q CustomQuery) labelNames() []string {
+	labels := append([]string{}, q.Labels...)
+	if q.ValueLabel != "" {
+		labels = append(labels, q.ValueLabel)
+	}
+	return labels
+}
+
+// valueType returns the type of the metric exported by the query.
+func (q CustomQuery) valueType() prometheus.ValueType {
+	if q.Type == customQueryTypeCounter {
+		return prometheus.CounterValue
+	}
+	return prometheus.GaugeValue
+}
+
+// newCustomScraper creates a scraper which exports the results of a custom query.
+func (c *Collector) newCustomScraper(q CustomQuery) scraper {
+	help := q.Help
+	if help == "" {
+		help = fmt.Sprintf("Metric exported from the custom query %s.", q.Name)
+	}
+	desc := prometheus.NewDesc(q.Name, help, q.labelNames(), c.constLabels)
+
+	return scraper{
+		name:     customCollectorPrefix + q.Name,
+		descs:    []*prometheus.Desc{desc},
+		interval: q.Interval,
+		scrape: func(ctx context.Context, db *sql.DB, metrics chan<- prometheus.Metric) (int, error) {
+			return c.collectCustomMetrics(ctx, db, q, desc, metrics)
+		},
+	}
+}
+
+func (c *Collector) collectCustomMetrics(ctx context.Context, db *sql.DB, q CustomQuery, desc *prometheus.Desc, metrics chan<- prometheus.Metric) (int, error) {
+	c.logger.Debug("Collecting custom metrics.", "query", q.Name)
+	rows, err := db.QueryContext(ctx, q.Query)
+	c.logger.Debug("Done querying custom metrics.", "query", q.Name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns: %w", err)
+	}
+	labelIndexes, err := columnIndexes(columns, q.Labels)
+	if err != nil {
+		return 0, err
+	}
+	valueIndexes, err := columnIndexes(columns, q.Values)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rows with the same label values would be duplicate series, which fail the whole scrape
+	// rather than only this query.
+	seen := map[string]bool{}
+	numRows := 0
+	for rows.Next() {
+		numRows++
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		labelValues := make([]string, 0, len(q.Labels)+1)
+		for _, i := range labelIndexes {
+			labelValues = append(labelValues, values[i].String)
+		}
+		key := strings.Join(labelValues, "\xff")
+		if seen[key] {
+			return 0, fmt.Errorf("%w: %s", errCustomDuplicateRow, strings.Join(labelValues, ", "))
+		}
+		seen[key] = true
+
+		for j, i := range valueIndexes {
+			if !values[i].Valid {
+				continue
+			}
+			value, err := strconv.ParseFloat(values[i].String, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse value of column %s: %w", q.Values[j], err)
+			}
+
+			sampleLabels := labelValues
+			if q.ValueLabel != "" {
+				sampleLabels = append(labelValues[:len(labelValues):len(labelValues)], q.Values[j])
+			}
+			metrics <- prometheus.MustNewConstMetric(desc, q.valueType(), value, sampleLabels...)
+		}
+	}
+
+	c.logger.Debug("Finished collecting custom metrics.", "query", q.Name)
+	return numRows, rows.Err()
+}
+
+// columnIndexes returns the index of each named column in the query results.
+// Names are matched case-insensitively, since Snowflake upper-cases unquoted identifiers.
+func columnIndexes(columns, names []string) ([]int, error) {
+	indexes := make([]int, 0, len(names))
+	for _, name := range names {
+		index := -1
+		for i, column := range columns {
+			if strings.EqualFold(column, name) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("column %s not found in query results", name)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
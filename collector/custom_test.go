@@ -0,0 +1,244 @@
+// Copyright  Grafana Labs
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCustomQueries(t *testing.T) {
+	t.Run("Valid file", func(t *testing.T) {
+		queries, err := LoadCustomQueries(filepath.Join("testdata", "custom_queries.yml"))
+		require.NoError(t, err)
+		require.Len(t, queries, 2)
+
+		require.Equal(t, "custom_table_rows", queries[0].Name)
+		require.Equal(t, []string{"table_catalog", "table_schema", "table_name"}, queries[0].Labels)
+		require.Equal(t, []string{"row_count", "bytes"}, queries[0].Values)
+		require.Equal(t, "column", queries[0].ValueLabel)
+		require.Equal(t, time.Hour, queries[0].Interval)
+
+		require.Equal(t, customQueryTypeCounter, queries[1].Type)
+		require.NoError(t, validateCustomQueries(queries))
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := LoadCustomQueries(filepath.Join("testdata", "does_not_exist.yml"))
+		require.ErrorContains(t, err, "failed to read custom queries file")
+	})
+}
+
+func TestValidateCustomQueries(t *testing.T) {
+	valid := CustomQuery{
+		Name:   "custom_metric",
+		Query:  "SELECT 1 AS VALUE;",
+		Values: []string{"value"},
+	}
+
+	testCases := []struct {
+		name        string
+		modify      func(q *CustomQuery)
+		expectedErr error
+	}{
+		{
+			name:   "Valid query",
+			modify: func(_ *CustomQuery) {},
+		},
+		{
+			name:        "No name",
+			modify:      func(q *CustomQuery) { q.Name = "" },
+			expectedErr: errCustomNoName,
+		},
+		{
+			name:        "Invalid name",
+			modify:      func(q *CustomQuery) { q.Name = "not-a-metric" },
+			expectedErr: errCustomInvalidName,
+		},
+		{
+			name:        "No query",
+			modify:      func(q *CustomQuery) { q.Query = " " },
+			expectedErr: errCustomNoQuery,
+		},
+		{
+			name:        "Invalid type",
+			modify:      func(q *CustomQuery) { q.Type = "histogram" },
+			expectedErr: errCustomInvalidType,
+		},
+		{
+			name:        "No values",
+			modify:      func(q *CustomQuery) { q.Values = nil },
+			expectedErr: errCustomNoValues,
+		},
+		{
+			name:        "Multiple values without value label",
+			modify:      func(q *CustomQuery) { q.Values = []string{"a", "b"} },
+			expectedErr: errCustomNoValueLabel,
+		},
+		{
+			name:        "Invalid label",
+			modify:      func(q *CustomQuery) { q.Labels = []string{"not-a-label"} },
+			expectedErr: errCustomInvalidLabel,
+		},
+		{
+			name:        "Built-in metric name",
+			modify:      func(q *CustomQuery) { q.Name = "snowflake_up" },
+			expectedErr: errCustomReservedName,
+		},
+		{
+			name:        "Duplicate label",
+			modify:      func(q *CustomQuery) { q.Labels = []string{"table_name", "table_name"} },
+			expectedErr: errCustomDuplicateLabel,
+		},
+		{
+			name: "Value label also a label",
+			modify: func(q *CustomQuery) {
+				q.Labels = []string{"column"}
+				q.ValueLabel = "column"
+			},
+			expectedErr: errCustomDuplicateLabel,
+		},
+		{
+			name:        "Account label",
+			modify:      func(q *CustomQuery) { q.Labels = []string{"account"} },
+			expectedErr: errCustomReservedLabel,
+		},
+		{
+			name:        "Reserved label prefix",
+			modify:      func(q *CustomQuery) { q.Labels = []string{"__name"} },
+			expectedErr: errCustomReservedLabel,
+		},
+		{
+			name:        "Negative interval",
+			modify:      func(q *CustomQuery) { q.Interval = -time.Minute },
+			expectedErr: errCustomIntervalInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := valid
+			tc.modify(&q)
+			err := validateCustomQueries([]CustomQuery{q})
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Duplicate name", func(t *testing.T) {
+		require.ErrorIs(t, validateCustomQueries([]CustomQuery{valid, valid}), errCustomDuplicateName)
+	})
+}
+
+func TestCollector_collectCustomMetrics(t *testing.T) {
+	query := CustomQuery{
+		Name:       "custom_table_rows",
+		Help:       "Number of rows in each table.",
+		Query:      "SELECT TABLE_NAME, ROW_COUNT, BYTES FROM ACCOUNT_USAGE.TABLES;",
+		Labels:     []string{"table_name"},
+		Values:     []string{"row_count", "bytes"},
+		ValueLabel: "column",
+	}
+
+	newMockDB := func(t *testing.T, rows *sqlmock.Rows) *sql.DB {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		mock.ExpectQuery(query.Query).WillReturnRows(rows).RowsWillBeClosed()
+		mock.ExpectClose()
+		t.Cleanup(func() { require.NoError(t, mock.ExpectationsWereMet()) })
+		return db
+	}
+
+	newCollector := func(t *testing.T, db *sql.DB) *Collector {
+		config := *ExampleConfig
+		config.Collectors = onlyCollectors()
+		config.CustomQueries = []CustomQuery{query}
+
+		col := NewCollector(promslog.NewNopLogger(), &config)
+		col.openDatabase = func(_ string) (*sql.DB, error) { return db, nil }
+		col.now = fixedNow
+		// Registered after the mock's cleanup, so the connection pool is closed before expectations are checked
+		t.Cleanup(func() { require.NoError(t, col.Close()) })
+		return col
+	}
+
+	t.Run("Metrics match expected", func(t *testing.T) {
+		db := newMockDB(t, sqlmock.NewRows([]string{"TABLE_NAME", "ROW_COUNT", "BYTES"}).
+			AddRow("mock_table", "10", "2048").
+			AddRow("another_mock_table", "20", nil))
+
+		expected := `
+# HELP custom_table_rows Number of rows in each table.
+# TYPE custom_table_rows gauge
+custom_table_rows{column="bytes",table_name="mock_table"} 2048
+custom_table_rows{column="row_count",table_name="another_mock_table"} 20
+custom_table_rows{column="row_count",table_name="mock_table"} 10
+# HELP snowflake_exporter_collector_rows Number of rows returned by Snowflake during the most recent run of the collector.
+# TYPE snowflake_exporter_collector_rows gauge
+snowflake_exporter_collector_rows{collector="custom.custom_table_rows"} 2
+# HELP snowflake_up Metric indicating the status of the exporter collection. 1 indicates that the connection Snowflake was successful, and all available metrics were collected. 0 indicates that the exporter failed to collect 1 or more metrics, due to an inability to connect to Snowflake.
+# TYPE snowflake_up gauge
+snowflake_up 1
+`
+		require.NoError(t, testutil.CollectAndCompare(newCollector(t, db), strings.NewReader(expected),
+			"custom_table_rows", "snowflake_exporter_collector_rows", "snowflake_up"))
+	})
+
+	t.Run("Missing column", func(t *testing.T) {
+		db := newMockDB(t, sqlmock.NewRows([]string{"TABLE_NAME", "ROW_COUNT"}).AddRow("mock_table", "10"))
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="custom.custom_table_rows"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(newCollector(t, db), strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+
+	t.Run("Duplicate label values", func(t *testing.T) {
+		db := newMockDB(t, sqlmock.NewRows([]string{"TABLE_NAME", "ROW_COUNT", "BYTES"}).
+			AddRow("mock_table", "10", "2048").
+			AddRow("mock_table", "20", "4096"))
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="custom.custom_table_rows"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(newCollector(t, db), strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+
+	t.Run("Non-numeric value", func(t *testing.T) {
+		db := newMockDB(t, sqlmock.NewRows([]string{"TABLE_NAME", "ROW_COUNT", "BYTES"}).AddRow("mock_table", "ten", "2048"))
+
+		expected := `
+# HELP snowflake_exporter_collector_success Whether the most recent run of the collector succeeded. 1 indicates success, 0 indicates failure.
+# TYPE snowflake_exporter_collector_success gauge
+snowflake_exporter_collector_success{collector="custom.custom_table_rows"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(newCollector(t, db), strings.NewReader(expected), "snowflake_exporter_collector_success"))
+	})
+}
@@ -80,4 +80,141 @@ const (
 	FROM ACCOUNT_USAGE.REPLICATION_USAGE_HISTORY
 	WHERE START_TIME >= dateadd(hour, -24, current_timestamp())
 	GROUP BY DATABASE_NAME, DATABASE_ID;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/query_history
+	// The configured dimension columns, each followed by a comma, are inserted into the select list.
+	queryHistoryMetricQuery = `SELECT %s count(*), sum(EXECUTION_TIME), sum(COMPILATION_TIME),
+		sum(QUEUED_PROVISIONING_TIME + QUEUED_REPAIR_TIME + QUEUED_OVERLOAD_TIME)
+	FROM ACCOUNT_USAGE.QUERY_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp())
+	GROUP BY ALL;`
+
+	queryHistoryErrorMetricQuery = `SELECT %s ERROR_CODE, count(*)
+	FROM ACCOUNT_USAGE.QUERY_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp()) AND ERROR_CODE IS NOT NULL
+	GROUP BY ALL;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/query_history
+	// The aggregates of the latency columns are inserted into the select list.
+	queryLatencyMetricQuery = `SELECT WAREHOUSE_NAME, count(*), %s
+	FROM ACCOUNT_USAGE.QUERY_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp()) AND WAREHOUSE_NAME IS NOT NULL
+	GROUP BY WAREHOUSE_NAME;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/query_history
+	queryEfficiencyMetricQuery = `SELECT WAREHOUSE_NAME, WAREHOUSE_SIZE, sum(BYTES_SPILLED_TO_LOCAL_STORAGE), sum(BYTES_SPILLED_TO_REMOTE_STORAGE),
+	sum(BYTES_SCANNED), sum(PARTITIONS_SCANNED), sum(PARTITIONS_TOTAL)
+	FROM ACCOUNT_USAGE.QUERY_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp()) AND WAREHOUSE_NAME IS NOT NULL
+	GROUP BY WAREHOUSE_NAME, WAREHOUSE_SIZE;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/task_history
+	taskRunMetricQuery = `SELECT NAME, SCHEMA_NAME, DATABASE_NAME, STATE, count(*)
+	FROM ACCOUNT_USAGE.TASK_HISTORY
+	WHERE SCHEDULED_TIME >= dateadd(hour, -24, current_timestamp()) AND STATE IN ('SUCCEEDED', 'FAILED', 'SKIPPED', 'CANCELLED')
+	GROUP BY NAME, SCHEMA_NAME, DATABASE_NAME, STATE;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/task_history
+	// Skipped runs never start, so the last run is the most recently completed run with a start time.
+	taskLastRunMetricQuery = `SELECT NAME, SCHEMA_NAME, DATABASE_NAME,
+		max_by(timestampdiff(millisecond, QUERY_START_TIME, COMPLETED_TIME), iff(QUERY_START_TIME IS NOT NULL, COMPLETED_TIME, NULL)),
+		date_part(epoch_second, max(iff(STATE = 'SUCCEEDED', COMPLETED_TIME, NULL)))
+	FROM ACCOUNT_USAGE.TASK_HISTORY
+	WHERE COMPLETED_TIME >= dateadd(day, -7, current_timestamp())
+	GROUP BY NAME, SCHEMA_NAME, DATABASE_NAME;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/serverless_task_history
+	serverlessTaskMetricQuery = `SELECT TASK_NAME, SCHEMA_NAME, DATABASE_NAME, sum(CREDITS_USED)
+	FROM ACCOUNT_USAGE.SERVERLESS_TASK_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp())
+	GROUP BY TASK_NAME, SCHEMA_NAME, DATABASE_NAME;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/pipe_usage_history
+	// The history only identifies pipes by name, so their schema and database are looked up in PIPES.
+	pipeUsageMetricQuery = `SELECT h.PIPE_NAME, p.PIPE_SCHEMA, p.PIPE_CATALOG, sum(h.CREDITS_USED), sum(h.BYTES_INSERTED), sum(h.FILES_INSERTED)
+	FROM ACCOUNT_USAGE.PIPE_USAGE_HISTORY h
+	LEFT JOIN ACCOUNT_USAGE.PIPES p ON h.PIPE_ID = p.PIPE_ID
+	WHERE h.START_TIME >= dateadd(hour, -24, current_timestamp())
+	GROUP BY h.PIPE_NAME, p.PIPE_SCHEMA, p.PIPE_CATALOG;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/copy_history
+	copyHistoryMetricQuery = `SELECT TABLE_NAME, TABLE_SCHEMA_NAME, TABLE_CATALOG_NAME,
+		sum(iff(LAST_LOAD_TIME >= dateadd(hour, -24, current_timestamp()), ERROR_COUNT, 0)),
+		date_part(epoch_second, max(LAST_LOAD_TIME))
+	FROM ACCOUNT_USAGE.COPY_HISTORY
+	WHERE LAST_LOAD_TIME >= dateadd(day, -7, current_timestamp())
+	GROUP BY TABLE_NAME, TABLE_SCHEMA_NAME, TABLE_CATALOG_NAME;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/materialized_view_refresh_history
+	materializedViewMetricQuery = `SELECT TABLE_NAME, TABLE_ID, SCHEMA_NAME, SCHEMA_ID, DATABASE_NAME, DATABASE_ID, sum(CREDITS_USED)
+	FROM ACCOUNT_USAGE.MATERIALIZED_VIEW_REFRESH_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp())
+	GROUP BY TABLE_NAME, TABLE_ID, SCHEMA_NAME, SCHEMA_ID, DATABASE_NAME, DATABASE_ID;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/search_optimization_history
+	searchOptimizationMetricQuery = `SELECT TABLE_NAME, TABLE_ID, SCHEMA_NAME, SCHEMA_ID, DATABASE_NAME, DATABASE_ID, sum(CREDITS_USED)
+	FROM ACCOUNT_USAGE.SEARCH_OPTIMIZATION_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp())
+	GROUP BY TABLE_NAME, TABLE_ID, SCHEMA_NAME, SCHEMA_ID, DATABASE_NAME, DATABASE_ID;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/dynamic_table_refresh_history
+	dynamicTableRefreshMetricQuery = `SELECT NAME, SCHEMA_NAME, DATABASE_NAME, STATE, count(*)
+	FROM ACCOUNT_USAGE.DYNAMIC_TABLE_REFRESH_HISTORY
+	WHERE DATA_TIMESTAMP >= dateadd(hour, -24, current_timestamp()) AND STATE IN ('SUCCEEDED', 'FAILED', 'UPSTREAM_FAILED', 'CANCELLED', 'SKIPPED')
+	GROUP BY NAME, SCHEMA_NAME, DATABASE_NAME, STATE;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/dynamic_table_refresh_history
+	// The lag is the age of the data of the most recent successful refresh, which is compared to the target lag.
+	dynamicTableLastRefreshMetricQuery = `SELECT NAME, SCHEMA_NAME, DATABASE_NAME,
+		max_by(timestampdiff(millisecond, REFRESH_START_TIME, REFRESH_END_TIME), iff(REFRESH_START_TIME IS NOT NULL, REFRESH_END_TIME, NULL)),
+		timestampdiff(second, max(iff(STATE = 'SUCCEEDED', DATA_TIMESTAMP, NULL)), current_timestamp()),
+		max_by(TARGET_LAG_SEC, DATA_TIMESTAMP),
+		date_part(epoch_second, max(iff(STATE = 'SUCCEEDED', REFRESH_END_TIME, NULL)))
+	FROM ACCOUNT_USAGE.DYNAMIC_TABLE_REFRESH_HISTORY
+	WHERE DATA_TIMESTAMP >= dateadd(day, -7, current_timestamp())
+	GROUP BY NAME, SCHEMA_NAME, DATABASE_NAME;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/data_transfer_history
+	dataTransferMetricQuery = `SELECT SOURCE_CLOUD, SOURCE_REGION, TARGET_CLOUD, TARGET_REGION, TRANSFER_TYPE, sum(BYTES_TRANSFERRED)
+	FROM ACCOUNT_USAGE.DATA_TRANSFER_HISTORY
+	WHERE START_TIME >= dateadd(hour, -24, current_timestamp())
+	GROUP BY SOURCE_CLOUD, SOURCE_REGION, TARGET_CLOUD, TARGET_REGION, TRANSFER_TYPE;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/warehouse_events_history
+	// Events are logged when they start and when they complete, so only completed events are counted.
+	warehouseEventMetricQuery = `SELECT WAREHOUSE_NAME, WAREHOUSE_ID, EVENT_NAME, count(*)
+	FROM ACCOUNT_USAGE.WAREHOUSE_EVENTS_HISTORY
+	WHERE TIMESTAMP >= dateadd(hour, -24, current_timestamp()) AND EVENT_STATE = 'COMPLETED'
+	GROUP BY WAREHOUSE_NAME, WAREHOUSE_ID, EVENT_NAME;`
+
+	// https://docs.snowflake.com/en/sql-reference/account-usage/warehouse_events_history
+	warehouseClusterMetricQuery = `SELECT WAREHOUSE_NAME, WAREHOUSE_ID, max(CLUSTER_COUNT)
+	FROM ACCOUNT_USAGE.WAREHOUSE_EVENTS_HISTORY
+	WHERE TIMESTAMP >= dateadd(hour, -24, current_timestamp())
+	GROUP BY WAREHOUSE_NAME, WAREHOUSE_ID;`
+
+	// https://docs.snowflake.com/en/sql-reference/sql/show-warehouses
+	warehouseStateMetricQuery = `SHOW WAREHOUSES;`
+
+	// https://docs.snowflake.com/en/sql-reference/sql/show-resource-monitors
+	resourceMonitorMetricQuery = `SHOW RESOURCE MONITORS;`
+
+	// https://docs.snowflake.com/en/sql-reference/organization-usage/usage_in_currency_daily
+	organizationUsageMetricQuery = `SELECT ACCOUNT_NAME, SERVICE_TYPE, USAGE_TYPE, CURRENCY, sum(USAGE_IN_CURRENCY)
+	FROM ORGANIZATION_USAGE.USAGE_IN_CURRENCY_DAILY
+	WHERE USAGE_DATE = (SELECT max(USAGE_DATE) FROM ORGANIZATION_USAGE.USAGE_IN_CURRENCY_DAILY)
+	GROUP BY ACCOUNT_NAME, SERVICE_TYPE, USAGE_TYPE, CURRENCY;`
+
+	// https://docs.snowflake.com/en/sql-reference/organization-usage/rate_sheet_daily
+	organizationRateMetricQuery = `SELECT ACCOUNT_NAME, SERVICE_TYPE, USAGE_TYPE, CURRENCY, max(EFFECTIVE_RATE)
+	FROM ORGANIZATION_USAGE.RATE_SHEET_DAILY
+	WHERE DATE = (SELECT max(DATE) FROM ORGANIZATION_USAGE.RATE_SHEET_DAILY)
+	GROUP BY ACCOUNT_NAME, SERVICE_TYPE, USAGE_TYPE, CURRENCY;`
+
+	// https://docs.snowflake.com/en/sql-reference/organization-usage/remaining_balance_daily
+	organizationBalanceMetricQuery = `SELECT CONTRACT_NUMBER, CURRENCY,
+		sum(CAPACITY_BALANCE), sum(FREE_USAGE_BALANCE), sum(ROLLOVER_BALANCE), sum(ON_DEMAND_CONSUMPTION_BALANCE)
+	FROM ORGANIZATION_USAGE.REMAINING_BALANCE_DAILY
+	WHERE DATE = (SELECT max(DATE) FROM ORGANIZATION_USAGE.REMAINING_BALANCE_DAILY)
+	GROUP BY CONTRACT_NUMBER, CURRENCY;`
 )
@@ -8,7 +8,7 @@
           {
             alert: 'SnowflakeWarnHighLoginFailures',
             expr: |||
-              100 * sum by (job, instance) (last_over_time(snowflake_failed_login_rate{%(filteringSelector)s}[1h])) / sum by (job, instance) (last_over_time(snowflake_login_rate{%(filteringSelector)s}[1h]))
+              100 * sum by (job, instance, account) (last_over_time(snowflake_failed_login_rate{%(filteringSelector)s}[1h])) / sum by (job, instance, account) (last_over_time(snowflake_login_rate{%(filteringSelector)s}[1h]))
               > %(alertsWarningLoginFailures)s
             ||| % this.config,
             'for': '5m',
@@ -25,7 +25,7 @@
           {
             alert: 'SnowflakeWarnHighComputeCreditUsage',
             expr: |||
-              sum by (job, instance) (last_over_time(snowflake_used_compute_credits{%(filteringSelector)s}[1h]))
+              sum by (job, instance, account) (last_over_time(snowflake_used_compute_credits{%(filteringSelector)s}[1h]))
               > 0.8 * %(alertsComputeCreditUsageLimit)s
             ||| % this.config,
             'for': '5m',
@@ -42,7 +42,7 @@
           {
             alert: 'SnowflakeCriticalHighComputeCreditUsage',
             expr: |||
-              sum by (job, instance) (last_over_time(snowflake_used_compute_credits{%(filteringSelector)s}[1h]))
+              sum by (job, instance, account) (last_over_time(snowflake_used_compute_credits{%(filteringSelector)s}[1h]))
               > %(alertsComputeCreditUsageLimit)s
             ||| % this.config,
             'for': '5m',
@@ -59,7 +59,7 @@
           {
             alert: 'SnowflakeWarnHighServiceCreditUsage',
             expr: |||
-              sum by (job, instance) (last_over_time(snowflake_used_cloud_services_credits{%(filteringSelector)s}[1h]))
+              sum by (job, instance, account) (last_over_time(snowflake_used_cloud_services_credits{%(filteringSelector)s}[1h]))
               > 0.8 * %(alertsServiceCreditUsageLimit)s
             ||| % this.config,
             'for': '5m',
@@ -76,7 +76,7 @@
           {
             alert: 'SnowflakeCriticalHighServiceCreditUsage',
             expr: |||
-              sum by (job, instance) (last_over_time(snowflake_used_cloud_services_credits{%(filteringSelector)s}[1h]))
+              sum by (job, instance, account) (last_over_time(snowflake_used_cloud_services_credits{%(filteringSelector)s}[1h]))
               > %(alertsServiceCreditUsageLimit)s
             ||| % this.config,
             'for': '5m',
@@ -90,6 +90,23 @@
                  'which is over %(alertsServiceCreditUsageLimit)s credits/hr.') % this.config,
             },
           },
+          {
+            alert: 'SnowflakeWarnHighResourceMonitorCreditUsage',
+            expr: |||
+              100 * max by (job, instance, account, name) (last_over_time(snowflake_resource_monitor_used_credits{%(filteringSelector)s}[1h])) / max by (job, instance, account, name) (last_over_time(snowflake_resource_monitor_credit_quota{%(filteringSelector)s}[1h]))
+              > %(alertsResourceMonitorCreditUsage)s
+            ||| % this.config,
+            'for': '5m',
+            labels: {
+              severity: 'warning',
+            },
+            annotations: {
+              summary: 'Resource monitor credit usage is close to its quota.',
+              description:
+                ('Resource monitor {{$labels.name}} has used {{ printf "%%.2f" $value }}%% of its credit quota on {{$labels.instance}}, ' +
+                 'which is above threshold of %(alertsResourceMonitorCreditUsage)s%%.') % this.config,
+            },
+          },
           {
             alert: 'SnowflakeDown',
             expr: 'last_over_time(snowflake_up{%(filteringSelector)s}[1h]) == 0' % this.config,
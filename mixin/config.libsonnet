@@ -19,6 +19,7 @@
   alertsWarningLoginFailures: '30',  // %
   alertsComputeCreditUsageLimit: '5',  // credits/hr
   alertsServiceCreditUsageLimit: '1',  // credits/hr
+  alertsResourceMonitorCreditUsage: '80',  // % of the resource monitor's credit quota
 
   signals+: {
     overview: (import './signals/overview.libsonnet')(this),